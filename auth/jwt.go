@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/farellandr/fullstack2024-test/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// JWTVerifier validates bearer tokens against the configured key, algorithm,
+// issuer and audience.
+type JWTVerifier struct {
+	parser      *jwt.Parser
+	keyFunc     jwt.Keyfunc
+	tenantClaim string
+}
+
+func NewJWTVerifier(cfg config.AuthConfig) (*JWTVerifier, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{cfg.Algorithm}),
+		jwt.WithLeeway(cfg.ClockSkew),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if len(cfg.Audiences) > 0 {
+		opts = append(opts, jwt.WithAudience(cfg.Audiences...))
+	}
+
+	v := &JWTVerifier{parser: jwt.NewParser(opts...), tenantClaim: cfg.TenantClaim}
+
+	switch cfg.Algorithm {
+	case "HS256":
+		secret := []byte(cfg.Secret)
+		v.keyFunc = func(*jwt.Token) (interface{}, error) {
+			return secret, nil
+		}
+	case "RS256":
+		keys, err := loadJWKSFile(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		v.keyFunc = func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			if key, ok := keys[kid]; ok {
+				return key, nil
+			}
+			// A set with a single key may be used by tokens that carry no kid.
+			if kid == "" && len(keys) == 1 {
+				for _, key := range keys {
+					return key, nil
+				}
+			}
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", cfg.Algorithm)
+	}
+
+	return v, nil
+}
+
+// Verify checks a raw token and returns the principal it authenticates.
+func (v *JWTVerifier) Verify(raw string) (*Principal, error) {
+	claims := jwt.MapClaims{}
+	if _, err := v.parser.ParseWithClaims(raw, claims, v.keyFunc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return nil, fmt.Errorf("%w: missing sub claim", ErrInvalidToken)
+	}
+
+	tenant, _ := claims[v.tenantClaim].(string)
+
+	return &Principal{Subject: subject, Method: MethodJWT, TenantID: strings.ToLower(tenant), Claims: claims}, nil
+}
@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/farellandr/fullstack2024-test/problem"
+	"github.com/gin-gonic/gin"
+)
+
+// Middleware rejects requests without a valid bearer token or X-API-Key
+// header and makes the authenticated principal, with its permissions,
+// available through CurrentPrincipal. A request carrying an API key is judged
+// on that key alone.
+func Middleware(verifier *JWTVerifier, apiKeys *APIKeyStore, roles *RoleStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if key := c.GetHeader("X-API-Key"); key != "" {
+			principal, err := apiKeys.Authenticate(c.Request.Context(), key)
+			if err != nil {
+				if !errors.Is(err, ErrInvalidAPIKey) {
+					problem.Respond(c, problem.Internal("Failed to verify API key", err))
+					return
+				}
+				problem.Respond(c, problem.Unauthorized("Invalid, expired or revoked API key"))
+				return
+			}
+
+			setPrincipal(c, principal)
+			c.Next()
+			return
+		}
+
+		header := c.GetHeader("Authorization")
+		scheme, token, found := strings.Cut(header, " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+			c.Header("WWW-Authenticate", `Bearer`)
+			problem.Respond(c, problem.Unauthorized("Missing bearer token or API key"))
+			return
+		}
+
+		principal, err := verifier.Verify(strings.TrimSpace(token))
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			problem.Respond(c, problem.Unauthorized("Invalid or expired token"))
+			return
+		}
+
+		principal.Permissions, err = roles.PermissionsFor(c.Request.Context(), principal.Subject, principal.TenantID)
+		if err != nil {
+			problem.Respond(c, problem.Internal("Failed to resolve permissions", err))
+			return
+		}
+
+		setPrincipal(c, principal)
+		c.Next()
+	}
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+const principalKey = "auth.principal"
+
+// Authentication methods recorded on a Principal.
+const (
+	MethodJWT    = "jwt"
+	MethodAPIKey = "api_key"
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	// Subject identifies the caller: the token's sub claim for users,
+	// "api-key:<key id>" for API keys.
+	Subject string
+	Method  string
+	// Permissions come from the scopes of an API key or the roles assigned
+	// to a user.
+	Permissions []string
+	APIKeyID    uint
+	// TenantID is the tenant the credentials are bound to, if any.
+	TenantID string
+	Claims   map[string]interface{}
+}
+
+func (p *Principal) HasPermission(perm string) bool {
+	for _, granted := range p.Permissions {
+		if granted == perm {
+			return true
+		}
+	}
+	return false
+}
+
+func setPrincipal(c *gin.Context, p *Principal) {
+	c.Set(principalKey, p)
+}
+
+// CurrentPrincipal returns the authenticated caller, or nil when the request
+// did not pass through the auth middleware.
+func CurrentPrincipal(c *gin.Context) *Principal {
+	if v, ok := c.Get(principalKey); ok {
+		if p, ok := v.(*Principal); ok {
+			return p
+		}
+	}
+	return nil
+}
+
+// TenantID returns the tenant the caller's credentials are bound to, or an
+// empty string for callers free to choose one.
+func TenantID(c *gin.Context) string {
+	if p := CurrentPrincipal(c); p != nil {
+		return p.TenantID
+	}
+	return ""
+}
+
+// Subject returns the authenticated caller's subject for audit records, or
+// an empty string for unauthenticated requests.
+func Subject(c *gin.Context) string {
+	if p := CurrentPrincipal(c); p != nil {
+		return p.Subject
+	}
+	return ""
+}
@@ -0,0 +1,220 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/farellandr/fullstack2024-test/cache"
+	"github.com/farellandr/fullstack2024-test/models"
+	"github.com/farellandr/fullstack2024-test/problem"
+	"github.com/farellandr/fullstack2024-test/tenancy"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Permissions checked by the API.
+const (
+	PermClientsRead   = "clients:read"
+	PermClientsWrite  = "clients:write"
+	PermClientsDelete = "clients:delete"
+	PermClientsLogo   = "clients:logo"
+	PermAPIKeysAdmin  = "api-keys:admin"
+	PermRolesAdmin    = "roles:admin"
+	PermCacheAdmin    = "cache:admin"
+	// PermTenantsAll lets a token not bound to a tenant act in any tenant,
+	// picked with the tenant header, and change the roles shared by all
+	// tenants. It only takes effect through assignments for AllTenants.
+	PermTenantsAll = "tenants:all"
+)
+
+// RoleAdmin is the built-in role holding every permission.
+const RoleAdmin = "admin"
+
+// permissionsCacheTTL bounds how long a changed role assignment can take to
+// reach other replicas.
+const permissionsCacheTTL = 30 * time.Second
+
+// permissionsCacheSize bounds the subject and tenant pairs whose permissions
+// are kept; the least recently used make way for new ones.
+const permissionsCacheSize = 10000
+
+var allPermissions = []string{
+	PermClientsRead, PermClientsWrite, PermClientsDelete, PermClientsLogo,
+	PermAPIKeysAdmin, PermRolesAdmin, PermCacheAdmin, PermTenantsAll,
+}
+
+// apiKeyPermissions are the permissions that may be granted to API keys as
+// scopes; administration is reserved for users.
+var apiKeyPermissions = map[string]bool{
+	PermClientsRead:   true,
+	PermClientsWrite:  true,
+	PermClientsDelete: true,
+	PermClientsLogo:   true,
+}
+
+// DefaultRoles are created on startup when missing.
+var DefaultRoles = []models.Role{
+	{Name: "viewer", Description: "Read-only access to clients", Permissions: []string{PermClientsRead}},
+	{Name: "editor", Description: "Create and edit clients and their logos", Permissions: []string{PermClientsRead, PermClientsWrite, PermClientsLogo}},
+	{Name: "manager", Description: "Editor who may also delete clients", Permissions: []string{PermClientsRead, PermClientsWrite, PermClientsLogo, PermClientsDelete}},
+	{Name: RoleAdmin, Description: "Full access, including API key, role and cache management", Permissions: allPermissions},
+}
+
+func IsKnownPermission(perm string) bool {
+	for _, p := range allPermissions {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAPIKeyScope reports whether perm can be granted to an API key.
+func IsAPIKeyScope(perm string) bool {
+	return apiKeyPermissions[perm]
+}
+
+// RoleStore resolves the permissions of users from their role assignments.
+type RoleStore struct {
+	DB *gorm.DB
+
+	cache *cache.LRU[[]string]
+}
+
+func NewRoleStore(db *gorm.DB) *RoleStore {
+	return &RoleStore{
+		DB:    db,
+		cache: cache.NewLRU[[]string](permissionsCacheSize, permissionsCacheTTL),
+	}
+}
+
+// permissionsKey keys cached permissions by subject, then tenant. Subjects
+// and tenants never contain a NUL byte.
+func permissionsKey(subject, tenant string) string {
+	return subject + "\x00" + tenant
+}
+
+// db returns a handle bound to ctx spanning all tenants: assignments are
+// resolved before the tenant of a request is known.
+func (s *RoleStore) db(ctx context.Context) *gorm.DB {
+	return s.DB.WithContext(tenancy.WithoutTenant(ctx))
+}
+
+// Seed creates the default roles that do not exist yet and assigns the admin
+// role in every tenant to the bootstrap subjects.
+func (s *RoleStore) Seed(adminSubjects []string) error {
+	ctx := context.Background()
+	for _, role := range DefaultRoles {
+		if err := s.db(ctx).Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "name"}}, DoNothing: true}).Create(&role).Error; err != nil {
+			return err
+		}
+	}
+
+	var admin models.Role
+	if err := s.db(ctx).Where("name = ?", RoleAdmin).First(&admin).Error; err != nil {
+		return err
+	}
+
+	// The admin role cannot be edited, so it picks up permissions added in
+	// later releases here.
+	if len(admin.Permissions) != len(allPermissions) {
+		if err := s.db(ctx).Model(&admin).Updates(models.Role{Permissions: allPermissions}).Error; err != nil {
+			return err
+		}
+	}
+
+	for _, subject := range adminSubjects {
+		assignment := models.RoleAssignment{TenantID: models.AllTenants, Subject: subject, RoleID: admin.ID, CreatedBy: "bootstrap"}
+		if err := s.db(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&assignment).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PermissionsFor returns the union of the permissions of every role assigned
+// to subject in tenant or in all tenants. For a token not bound to a tenant,
+// tenant is empty and only assignments for all tenants count.
+func (s *RoleStore) PermissionsFor(ctx context.Context, subject, tenant string) ([]string, error) {
+	key := permissionsKey(subject, tenant)
+	if permissions, ok := s.cache.Get(key); ok {
+		return permissions, nil
+	}
+
+	tenants := []string{models.AllTenants}
+	if tenant != "" {
+		tenants = append(tenants, tenant)
+	}
+
+	var roles []models.Role
+	err := s.db(ctx).Joins("JOIN my_role_assignment ON my_role_assignment.role_id = my_role.id").
+		Where("my_role_assignment.subject = ? AND my_role_assignment.tenant_id IN ?", subject, tenants).
+		Find(&roles).Error
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	permissions := []string{}
+	for _, role := range roles {
+		for _, p := range role.Permissions {
+			if !seen[p] {
+				seen[p] = true
+				permissions = append(permissions, p)
+			}
+		}
+	}
+
+	s.cache.Set(key, permissions, permissionsCacheTTL)
+
+	return permissions, nil
+}
+
+// Invalidate drops cached permissions, for one subject in every tenant or,
+// when subject is empty, for everyone.
+func (s *RoleStore) Invalidate(subject string) {
+	if subject == "" {
+		s.cache.Purge()
+		return
+	}
+	for _, key := range s.cache.Keys() {
+		if strings.HasPrefix(key, permissionsKey(subject, "")) {
+			s.cache.Delete(key)
+		}
+	}
+}
+
+// Can reports whether the caller of the request holds perm. Handlers use it
+// for checks that depend on the request body or the loaded record.
+func Can(c *gin.Context, perm string) bool {
+	p := CurrentPrincipal(c)
+	return p != nil && p.HasPermission(perm)
+}
+
+// CrossTenant reports whether the caller may act in a tenant of its choosing.
+// Only callers whose credentials are not bound to a tenant can hold
+// PermTenantsAll through an assignment that applies.
+func CrossTenant(c *gin.Context) bool {
+	p := CurrentPrincipal(c)
+	return p != nil && p.TenantID == "" && p.HasPermission(PermTenantsAll)
+}
+
+// Require rejects requests whose caller does not hold perm.
+func Require(perm string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if CurrentPrincipal(c) == nil {
+			problem.Respond(c, problem.Unauthorized("Authentication required"))
+			return
+		}
+
+		if !Can(c, perm) {
+			problem.Respond(c, problem.Forbidden("Missing permission "+perm))
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,64 @@
+// Package cache stores opaque values by key with a TTL. Handlers work against
+// the Cache interface; the backend is chosen by configuration.
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/farellandr/fullstack2024-test/config"
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrMiss is returned for keys that are not cached.
+var ErrMiss = errors.New("cache miss")
+
+// Cache is a key-value store with expiring entries.
+type Cache interface {
+	// Get returns the value stored at key, or ErrMiss.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// MGet returns the values stored at keys, in order, with nil for those
+	// that are not cached.
+	MGet(ctx context.Context, keys ...string) ([][]byte, error)
+	// Set stores value at key for ttl; a zero ttl stores it without expiry.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// SetMany stores several entries at once, in a single round trip where
+	// the backend allows.
+	SetMany(ctx context.Context, items ...Item) error
+	// Delete removes keys; keys that are not cached are ignored.
+	Delete(ctx context.Context, keys ...string) error
+	// Close releases the resources held by the backend.
+	Close() error
+}
+
+// Item is an entry written by SetMany.
+type Item struct {
+	Key   string
+	Value []byte
+	TTL   time.Duration
+}
+
+// Scanner is implemented by backends that can list their keys.
+type Scanner interface {
+	// Scan calls fn with batches of about count keys matching the glob
+	// pattern match, until the keys are exhausted or fn fails. A key may be
+	// seen more than once.
+	Scan(ctx context.Context, match string, count int, fn func(keys []string) error) error
+}
+
+// New returns the backend selected by cfg.Backend. The Redis backend uses
+// client, which the caller keeps ownership of.
+func New(cfg config.CacheConfig, client *redis.Client) (Cache, error) {
+	switch cfg.Backend {
+	case config.CacheBackendRedis:
+		return NewRedis(client, cfg.LocalSize, cfg.LocalTTL), nil
+	case config.CacheBackendMemory:
+		return NewMemory(cfg.LocalSize), nil
+	case config.CacheBackendNone:
+		return Noop{}, nil
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q", cfg.Backend)
+	}
+}
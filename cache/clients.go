@@ -0,0 +1,230 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/farellandr/fullstack2024-test/config"
+	"github.com/farellandr/fullstack2024-test/models"
+	"github.com/google/uuid"
+)
+
+// ErrNotFound is returned for slugs recently found not to exist.
+var ErrNotFound = errors.New("cached as not found")
+
+// Clients caches clients and client list pages per tenant on top of a Cache
+// backend, in the versioned encoding of codec.go.
+type Clients struct {
+	Cache  Cache
+	Config config.CacheConfig
+	// Popularity counts lookups per client, for warm-ups.
+	Popularity *Popularity
+}
+
+func NewClients(c Cache, cfg config.CacheConfig) *Clients {
+	return &Clients{Cache: c, Config: cfg, Popularity: NewPopularity(popularityTrackSize)}
+}
+
+// cachedClient is the value stored for a client: the client while it
+// exists, or a marker that it does not.
+type cachedClient struct {
+	FreshUntil int64          `json:"f"`
+	Missing    bool           `json:"m,omitempty"`
+	Data       *models.Client `json:"d,omitempty"`
+}
+
+// Set caches a client after it changed, for the configured TTL varied by
+// jitter and kept for StaleTTL beyond that. Cached client lists of the
+// tenant are invalidated.
+func (c *Clients) Set(ctx context.Context, tenant, slug string, client *models.Client) error {
+	if err := c.invalidateLists(ctx, tenant); err != nil {
+		return err
+	}
+	return c.Fill(ctx, tenant, slug, client)
+}
+
+// Fill caches a client freshly read from the database, like Set but
+// leaving client lists alone: nothing changed.
+func (c *Clients) Fill(ctx context.Context, tenant, slug string, client *models.Client) error {
+	item, err := c.freshItem(tenant, slug, client)
+	if err != nil {
+		return err
+	}
+	return c.Cache.Set(ctx, item.Key, item.Value, item.TTL)
+}
+
+// FillMany caches several clients freshly read from the database at once,
+// each under its own tenant and slug.
+func (c *Clients) FillMany(ctx context.Context, clients []models.Client) error {
+	items := make([]Item, len(clients))
+	for i := range clients {
+		item, err := c.freshItem(clients[i].TenantID, clients[i].Slug, &clients[i])
+		if err != nil {
+			return err
+		}
+		items[i] = item
+	}
+	return c.Cache.SetMany(ctx, items...)
+}
+
+// freshItem encodes a client as an entry fresh for the configured TTL
+// varied by jitter, and kept for StaleTTL beyond that.
+func (c *Clients) freshItem(tenant, slug string, client *models.Client) (Item, error) {
+	ttl := c.jitteredTTL()
+	value, err := encode(cachedClient{
+		FreshUntil: time.Now().Add(ttl).UnixMilli(),
+		Data:       client,
+	})
+	if err != nil {
+		return Item{}, err
+	}
+	return Item{Key: clientKey(tenant, slug), Value: value, TTL: ttl + c.Config.StaleTTL}, nil
+}
+
+// SetMissing remembers for NegativeTTL that slug does not exist, so repeated
+// lookups of an unknown slug stay off the database. Creating the client
+// overwrites the marker.
+func (c *Clients) SetMissing(ctx context.Context, tenant, slug string) error {
+	if c.Config.NegativeTTL <= 0 {
+		return nil
+	}
+
+	return c.set(ctx, clientKey(tenant, slug), cachedClient{
+		FreshUntil: time.Now().Add(c.Config.NegativeTTL).UnixMilli(),
+		Missing:    true,
+	}, c.Config.NegativeTTL)
+}
+
+func (c *Clients) set(ctx context.Context, key string, entry cachedClient, ttl time.Duration) error {
+	value, err := encode(entry)
+	if err != nil {
+		return err
+	}
+	return c.Cache.Set(ctx, key, value, ttl)
+}
+
+// Get returns a cached client and whether it is past its TTL and due for a
+// refresh. It fails with ErrMiss when nothing usable is cached and
+// ErrNotFound when the slug is known not to exist.
+func (c *Clients) Get(ctx context.Context, tenant, slug string) (*models.Client, bool, error) {
+	value, err := c.Cache.Get(ctx, clientKey(tenant, slug))
+	if err != nil {
+		return nil, false, err
+	}
+
+	var entry cachedClient
+	if err := decode(value, &entry); err != nil || (entry.Data == nil && !entry.Missing) {
+		// Entries of another schema version, or unreadable ones, are
+		// refetched and overwritten.
+		return nil, false, ErrMiss
+	}
+
+	if entry.Missing {
+		return nil, false, ErrNotFound
+	}
+	stale := time.Now().UnixMilli() >= entry.FreshUntil
+	return entry.Data, stale, nil
+}
+
+// Delete evicts a client and invalidates cached client lists of the tenant.
+// The client is evicted even when the lists cannot be invalidated, so that
+// a renamed or deleted client is never served under its old slug.
+func (c *Clients) Delete(ctx context.Context, tenant, slug string) error {
+	evictErr := c.Cache.Delete(ctx, clientKey(tenant, slug))
+	return errors.Join(evictErr, c.invalidateLists(ctx, tenant))
+}
+
+// jitteredTTL spreads expiries over TTL ± TTLJitter.
+func (c *Clients) jitteredTTL() time.Duration {
+	ttl := c.Config.TTL
+	if c.Config.TTLJitter > 0 {
+		ttl += time.Duration((rand.Float64()*2 - 1) * c.Config.TTLJitter * float64(ttl))
+	}
+	return ttl
+}
+
+// Cached client lists are keyed by a per-tenant generation token. Every
+// client change replaces it, which orphans all pages cached under the
+// previous generation at once; they are never read again and expire after
+// ListTTL.
+//
+// A page computed while a change commits is stored under the generation read
+// before the change, so it cannot outlive it.
+
+// ListGeneration returns the current list generation of a tenant, starting
+// a new one if there is none.
+func (c *Clients) ListGeneration(ctx context.Context, tenant string) (string, error) {
+	gen, err := c.Cache.Get(ctx, listGenerationKey(tenant))
+	if errors.Is(err, ErrMiss) {
+		return c.newListGeneration(ctx, tenant)
+	}
+	return string(gen), err
+}
+
+// GetList decodes into v the list response cached for a normalised query
+// under generation gen, or fails with ErrMiss.
+func (c *Clients) GetList(ctx context.Context, tenant, gen, query string, v interface{}) error {
+	if c.Config.ListTTL <= 0 {
+		return ErrMiss
+	}
+
+	value, err := c.Cache.Get(ctx, clientListKey(tenant, gen, query))
+	if err != nil {
+		return err
+	}
+	if err := decode(value, v); err != nil {
+		return ErrMiss
+	}
+	return nil
+}
+
+// SetList caches list response v for a normalised query under generation
+// gen for ListTTL.
+func (c *Clients) SetList(ctx context.Context, tenant, gen, query string, v interface{}) error {
+	if c.Config.ListTTL <= 0 {
+		return nil
+	}
+
+	value, err := encode(v)
+	if err != nil {
+		return err
+	}
+	return c.Cache.Set(ctx, clientListKey(tenant, gen, query), value, c.Config.ListTTL)
+}
+
+func (c *Clients) invalidateLists(ctx context.Context, tenant string) error {
+	_, err := c.newListGeneration(ctx, tenant)
+	return err
+}
+
+func (c *Clients) newListGeneration(ctx context.Context, tenant string) (string, error) {
+	gen := uuid.NewString()
+	if err := c.Cache.Set(ctx, listGenerationKey(tenant), []byte(gen), 0); err != nil {
+		return "", err
+	}
+	return gen, nil
+}
+
+// clientKey namespaces cached clients by tenant, so that equal slugs in two
+// tenants never share an entry.
+func clientKey(tenant, slug string) string {
+	return "tenant:" + tenant + ":client:" + slug
+}
+
+func listGenerationKey(tenant string) string {
+	return "tenant:" + tenant + ":clients:gen"
+}
+
+// clientListKey hashes the query so that arbitrary search text keeps keys
+// short and free of separators. The schema version is part of the key:
+// replicas still running an older release serve cached pages without
+// decoding them, so they must never find pages in the current encoding.
+func clientListKey(tenant, gen, query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return "tenant:" + tenant + ":clients:" + gen + ":v" + strconv.Itoa(SchemaVersion) + ":" + hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+)
+
+// SchemaVersion identifies the shape of cached clients and client lists.
+// Bump it whenever models.Client, models.Tag or a cached response changes
+// shape: entries written under another version read as misses and are
+// refetched, rather than decoded wrongly.
+//
+// Version 1 is the bare JSON cached before values carried a header; it is
+// never written with one, and decode rejects it by the missing magic byte.
+// Versions must fit in the single header byte.
+const SchemaVersion = 2
+
+// Every encoded value starts with a header: a magic byte, the schema
+// version and the payload encoding. Values cached before versioning began
+// are bare JSON and so never carry the magic byte.
+const (
+	codecMagic  = 0xCA
+	headerSize  = 3
+	rawJSON     = 0
+	gzippedJSON = 1
+)
+
+// compressMin is the payload size from which JSON is gzipped; below it the
+// gzip framing outweighs the saving.
+const compressMin = 512
+
+var (
+	// errOutdated is returned for a value written under another schema
+	// version, or before versioning began.
+	errOutdated = errors.New("cached value has an outdated schema version")
+	errCorrupt  = errors.New("cached value is corrupt")
+)
+
+var gzipWriters = sync.Pool{
+	New: func() interface{} {
+		w, _ := gzip.NewWriterLevel(nil, gzip.BestSpeed)
+		return w
+	},
+}
+
+// encode renders v as JSON behind a header for the current schema version,
+// gzipping it when large enough to be worth it.
+func encode(v interface{}) ([]byte, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(payload) < compressMin {
+		return append([]byte{codecMagic, SchemaVersion, rawJSON}, payload...), nil
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(headerSize + len(payload)/2)
+	buf.Write([]byte{codecMagic, SchemaVersion, gzippedJSON})
+
+	w := gzipWriters.Get().(*gzip.Writer)
+	defer gzipWriters.Put(w)
+	w.Reset(&buf)
+	if _, err := w.Write(payload); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decode reads a value written by encode into v. It fails with errOutdated
+// for values of another schema version and errCorrupt for unreadable ones.
+func decode(value []byte, v interface{}) error {
+	if len(value) < headerSize || value[0] != codecMagic || value[1] != SchemaVersion {
+		return errOutdated
+	}
+
+	payload := value[headerSize:]
+	switch value[2] {
+	case rawJSON:
+	case gzippedJSON:
+		r, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return errCorrupt
+		}
+		if payload, err = io.ReadAll(r); err != nil {
+			return errCorrupt
+		}
+	default:
+		return errCorrupt
+	}
+
+	if err := json.Unmarshal(payload, v); err != nil {
+		return errCorrupt
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/farellandr/fullstack2024-test/models"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		value   cachedClient
+		gzipped bool
+	}{
+		{"small client", cachedClient{Data: &models.Client{ID: 1, Name: "Acme", Slug: "acme"}, FreshUntil: 42}, false},
+		{"negative entry", cachedClient{Missing: true}, false},
+		{"large client", cachedClient{Data: &models.Client{ID: 2, Name: "Acme", Address: strings.Repeat("Jl. Sudirman ", 100)}}, true},
+	} {
+		encoded, err := encode(tc.value)
+		if err != nil {
+			t.Fatalf("%s: encode: %v", tc.name, err)
+		}
+		if encoded[0] != codecMagic || encoded[1] != SchemaVersion {
+			t.Errorf("%s: header % x, want magic and version %d", tc.name, encoded[:2], SchemaVersion)
+		}
+		if gzipped := encoded[2] == gzippedJSON; gzipped != tc.gzipped {
+			t.Errorf("%s: gzipped = %v, want %v", tc.name, gzipped, tc.gzipped)
+		}
+
+		var decoded cachedClient
+		if err := decode(encoded, &decoded); err != nil {
+			t.Fatalf("%s: decode: %v", tc.name, err)
+		}
+		want, _ := json.Marshal(tc.value)
+		if got, _ := json.Marshal(decoded); !bytes.Equal(got, want) {
+			t.Errorf("%s: decoded %s, want %s", tc.name, got, want)
+		}
+	}
+}
+
+func TestCodecGzipThreshold(t *testing.T) {
+	for _, tc := range []struct {
+		size    int
+		gzipped bool
+	}{
+		{compressMin - 1, false},
+		{compressMin, true},
+	} {
+		// A JSON string of n bytes: the quotes plus n-2 letters.
+		value := strings.Repeat("a", tc.size-2)
+		encoded, err := encode(value)
+		if err != nil {
+			t.Fatalf("encode %d bytes: %v", tc.size, err)
+		}
+		if gzipped := encoded[2] == gzippedJSON; gzipped != tc.gzipped {
+			t.Errorf("%d-byte payload: gzipped = %v, want %v", tc.size, gzipped, tc.gzipped)
+		}
+
+		var decoded string
+		if err := decode(encoded, &decoded); err != nil || decoded != value {
+			t.Errorf("%d-byte payload: decode returned %d bytes, %v", tc.size, len(decoded), err)
+		}
+	}
+}
+
+func TestCodecRejects(t *testing.T) {
+	current, err := encode(cachedClient{Data: &models.Client{ID: 1}})
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	withHeader := func(version, encoding byte, payload string) []byte {
+		return append([]byte{codecMagic, version, encoding}, payload...)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		value []byte
+		want  error
+	}{
+		{"legacy raw JSON", []byte(`{"Data":{"ID":1},"FreshUntil":0}`), errOutdated},
+		{"older version", withHeader(SchemaVersion-1, rawJSON, string(current[headerSize:])), errOutdated},
+		{"newer version", withHeader(SchemaVersion+1, rawJSON, string(current[headerSize:])), errOutdated},
+		{"truncated header", current[:2], errOutdated},
+		{"empty", nil, errOutdated},
+		{"unknown encoding", withHeader(SchemaVersion, 7, `{}`), errCorrupt},
+		{"bad gzip", withHeader(SchemaVersion, gzippedJSON, "not gzip"), errCorrupt},
+		{"bad JSON", withHeader(SchemaVersion, rawJSON, "{"), errCorrupt},
+	} {
+		var decoded cachedClient
+		if err := decode(tc.value, &decoded); !errors.Is(err, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,348 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/farellandr/fullstack2024-test/models"
+	"github.com/farellandr/fullstack2024-test/tenancy"
+	"gorm.io/gorm"
+)
+
+// Consistency check modes.
+const (
+	// ModeReport only reports drift.
+	ModeReport = "report"
+	// ModeRepair rewrites entries of live clients from the database and
+	// evicts the others.
+	ModeRepair = "repair"
+	// ModeEvict evicts every drifted entry.
+	ModeEvict = "evict"
+)
+
+// Kinds of drift between a cached client and its row.
+const (
+	// DriftStale is a cached client that differs from its row.
+	DriftStale = "stale"
+	// DriftDeleted is a cached client whose row is soft-deleted.
+	DriftDeleted = "deleted"
+	// DriftOrphaned is a cached client without a row.
+	DriftOrphaned = "orphaned"
+	// DriftNotFound is a not-found marker for a client that exists.
+	DriftNotFound = "not_found_but_exists"
+	// DriftOutdated is an entry of another schema version.
+	DriftOutdated = "outdated"
+	// DriftCorrupt is an entry that cannot be decoded.
+	DriftCorrupt = "corrupt"
+)
+
+// Actions taken on a drifted entry.
+const (
+	ActionNone     = "none"
+	ActionRepaired = "repaired"
+	ActionEvicted  = "evicted"
+	ActionFailed   = "failed"
+)
+
+// ConsistencyOptions control a consistency check.
+type ConsistencyOptions struct {
+	Mode string
+	// Tenant restricts the check to one tenant; empty checks them all.
+	Tenant string
+	// Rate caps the keys checked per second, keeping the load on Redis and
+	// Postgres bounded.
+	Rate int
+	// Limit stops the check after this many keys; zero checks them all.
+	Limit int
+	// BatchSize is the number of keys fetched and compared at once.
+	BatchSize int
+	// Progress, when set, is called with the report so far after every
+	// batch.
+	Progress func(ConsistencyReport)
+}
+
+// validate checks opts before a check runs against clients.
+func (opts ConsistencyOptions) validate(clients *Clients) error {
+	switch opts.Mode {
+	case ModeReport, ModeRepair, ModeEvict:
+	default:
+		return fmt.Errorf("unknown consistency check mode %q", opts.Mode)
+	}
+	if opts.Rate <= 0 || opts.BatchSize <= 0 {
+		return errors.New("consistency check rate and batch size must be positive")
+	}
+	if _, ok := clients.Cache.(Scanner); !ok {
+		return ErrNotScannable
+	}
+	return nil
+}
+
+// Drift is one cache entry that disagrees with the database.
+type Drift struct {
+	Key    string
+	Tenant string
+	Slug   string
+	Kind   string
+	Action string
+	Error  string `json:",omitempty"`
+}
+
+// ConsistencyReport is the outcome of a consistency check.
+type ConsistencyReport struct {
+	Mode       string
+	Scanned    int
+	Consistent int
+	Drift      []Drift
+	Repaired   int
+	Evicted    int
+	// Truncated is set when Limit stopped the check early.
+	Truncated bool
+}
+
+// ErrNotScannable is returned when the cache backend cannot list its keys.
+var ErrNotScannable = errors.New("cache backend cannot list its keys")
+
+var errLimitReached = errors.New("consistency check limit reached")
+
+// CheckConsistency compares every cached client with its row, soft-deleted
+// rows included, and repairs or evicts drifted entries as opts.Mode says.
+// Cached client lists are not checked: any repair or eviction invalidates
+// them.
+func CheckConsistency(ctx context.Context, db *gorm.DB, clients *Clients, opts ConsistencyOptions) (ConsistencyReport, error) {
+	report := ConsistencyReport{Mode: opts.Mode, Drift: []Drift{}}
+	if err := opts.validate(clients); err != nil {
+		return report, err
+	}
+	scanner := clients.Cache.(Scanner)
+
+	tenant := opts.Tenant
+	if tenant == "" {
+		tenant = "*"
+	}
+
+	c := &checker{
+		db:       db.WithContext(tenancy.WithoutTenant(ctx)),
+		clients:  clients,
+		opts:     opts,
+		report:   &report,
+		interval: time.Second / time.Duration(opts.Rate),
+		next:     time.Now(),
+	}
+	err := scanner.Scan(ctx, clientKey(tenant, "*"), opts.BatchSize, func(keys []string) error {
+		if err := c.check(ctx, keys); err != nil {
+			return err
+		}
+		if opts.Progress != nil {
+			opts.Progress(report)
+		}
+		return nil
+	})
+	if errors.Is(err, errLimitReached) {
+		report.Truncated = true
+		err = nil
+	}
+	return report, err
+}
+
+type checker struct {
+	db      *gorm.DB
+	clients *Clients
+	opts    ConsistencyOptions
+	report  *ConsistencyReport
+
+	interval time.Duration
+	next     time.Time
+}
+
+func (c *checker) check(ctx context.Context, keys []string) error {
+	if c.opts.Limit > 0 {
+		left := c.opts.Limit - c.report.Scanned
+		if left <= 0 {
+			return errLimitReached
+		}
+		if len(keys) > left {
+			keys = keys[:left]
+			c.report.Truncated = true
+		}
+	}
+
+	if err := c.pace(ctx, len(keys)); err != nil {
+		return err
+	}
+
+	values, err := c.clients.Cache.MGet(ctx, keys...)
+	if err != nil {
+		return err
+	}
+
+	// Keys are grouped by tenant so each tenant's rows load in one query.
+	byTenant := make(map[string]map[string]int)
+	for i, key := range keys {
+		if values[i] == nil {
+			// Expired or evicted since the scan saw it.
+			continue
+		}
+		c.report.Scanned++
+
+		tenant, slug, ok := parseClientKey(key)
+		if !ok {
+			continue
+		}
+		if byTenant[tenant] == nil {
+			byTenant[tenant] = make(map[string]int)
+		}
+		byTenant[tenant][slug] = i
+	}
+
+	for tenant, slugs := range byTenant {
+		rows, err := c.load(tenant, slugs)
+		if err != nil {
+			return err
+		}
+		for slug, i := range slugs {
+			c.compare(ctx, keys[i], tenant, slug, values[i], rows[slug])
+		}
+	}
+
+	return nil
+}
+
+// pace waits until n more keys may be checked without exceeding the rate.
+func (c *checker) pace(ctx context.Context, n int) error {
+	wait := time.Until(c.next)
+	c.next = time.Now().Add(max(wait, 0) + time.Duration(n)*c.interval)
+	if wait <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// load reads the rows for slugs, preferring a live row where a soft-deleted
+// one shares its slug.
+func (c *checker) load(tenant string, slugs map[string]int) (map[string]*models.Client, error) {
+	names := make([]string, 0, len(slugs))
+	for slug := range slugs {
+		names = append(names, slug)
+	}
+
+	// Live rows sort last and so win in bySlug.
+	var rows []models.Client
+	err := c.db.Unscoped().Preload("Tags").
+		Where("tenant_id = ? AND slug IN ?", tenant, names).
+		Order("deleted_at IS NULL, id").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	bySlug := make(map[string]*models.Client, len(rows))
+	for i := range rows {
+		bySlug[rows[i].Slug] = &rows[i]
+	}
+	return bySlug, nil
+}
+
+func (c *checker) compare(ctx context.Context, key, tenant, slug string, value []byte, row *models.Client) {
+	live := row != nil && !row.DeletedAt.Valid
+
+	var kind string
+	var entry cachedClient
+	decodeErr := decode(value, &entry)
+	switch {
+	case errors.Is(decodeErr, errOutdated):
+		kind = DriftOutdated
+	case decodeErr != nil || (entry.Data == nil && !entry.Missing):
+		kind = DriftCorrupt
+	case entry.Missing && live:
+		kind = DriftNotFound
+	case entry.Missing:
+		c.report.Consistent++
+		return
+	case row == nil:
+		kind = DriftOrphaned
+	case !live:
+		kind = DriftDeleted
+	case !sameClient(entry.Data, row):
+		kind = DriftStale
+	default:
+		c.report.Consistent++
+		return
+	}
+
+	drift := Drift{Key: key, Tenant: tenant, Slug: slug, Kind: kind, Action: ActionNone}
+
+	var err error
+	switch {
+	case c.opts.Mode == ModeRepair && live:
+		if err = c.clients.Set(ctx, tenant, slug, row); err == nil {
+			drift.Action = ActionRepaired
+			c.report.Repaired++
+		}
+	case c.opts.Mode == ModeRepair || c.opts.Mode == ModeEvict:
+		if err = c.clients.Delete(ctx, tenant, slug); err == nil {
+			drift.Action = ActionEvicted
+			c.report.Evicted++
+		}
+	}
+	if err != nil {
+		drift.Action = ActionFailed
+		drift.Error = err.Error()
+	}
+
+	c.report.Drift = append(c.report.Drift, drift)
+}
+
+// sameClient reports whether a cached client matches row. Both sides are
+// normalised first: timestamps lose precision in the database, and a client
+// cached straight after creation has no tags loaded.
+func sameClient(cached *models.Client, row *models.Client) bool {
+	a, errA := json.Marshal(normalizeClient(*cached))
+	b, errB := json.Marshal(normalizeClient(*row))
+	return errA == nil && errB == nil && bytes.Equal(a, b)
+}
+
+func normalizeClient(c models.Client) models.Client {
+	c.CreatedAt = normalizeTime(c.CreatedAt)
+	c.UpdatedAt = normalizeTime(c.UpdatedAt)
+	if c.DeletedAt.Valid {
+		c.DeletedAt.Time = normalizeTime(c.DeletedAt.Time)
+	}
+
+	tags := make([]models.Tag, len(c.Tags))
+	for i, tag := range c.Tags {
+		tag.CreatedAt = normalizeTime(tag.CreatedAt)
+		tag.UpdatedAt = normalizeTime(tag.UpdatedAt)
+		tags[i] = tag
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].ID < tags[j].ID })
+	c.Tags = tags
+
+	return c
+}
+
+func normalizeTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
+
+// parseClientKey splits a key made by clientKey.
+func parseClientKey(key string) (tenant, slug string, ok bool) {
+	rest, ok := strings.CutPrefix(key, "tenant:")
+	if !ok {
+		return "", "", false
+	}
+	return strings.Cut(rest, ":client:")
+}
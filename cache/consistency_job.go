@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/farellandr/fullstack2024-test/logging"
+	"gorm.io/gorm"
+)
+
+// ErrCheckRunning is returned when a consistency check is started for a
+// tenant whose previous check still runs.
+var ErrCheckRunning = errors.New("a consistency check is already running")
+
+// ConsistencyStatus describes the running or last consistency check of a
+// tenant.
+type ConsistencyStatus struct {
+	State      string
+	StartedAt  *time.Time `json:",omitempty"`
+	FinishedAt *time.Time `json:",omitempty"`
+	Error      string     `json:",omitempty"`
+	// Report is updated after every batch while the check runs, and kept
+	// when it fails or is canceled: repairs and evictions it lists were
+	// applied.
+	Report *ConsistencyReport `json:",omitempty"`
+}
+
+// ConsistencyChecker runs consistency checks in the background, one at a
+// time per tenant.
+type ConsistencyChecker struct {
+	DB      *gorm.DB
+	Clients *Clients
+
+	mu     sync.Mutex
+	checks map[string]*consistencyCheck
+	wg     sync.WaitGroup
+}
+
+type consistencyCheck struct {
+	status ConsistencyStatus
+	cancel context.CancelFunc
+}
+
+func NewConsistencyChecker(db *gorm.DB, clients *Clients) *ConsistencyChecker {
+	return &ConsistencyChecker{
+		DB:      db,
+		Clients: clients,
+		checks:  make(map[string]*consistencyCheck),
+	}
+}
+
+// Status returns the running or last check of tenant.
+func (c *ConsistencyChecker) Status(tenant string) ConsistencyStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if check, ok := c.checks[tenant]; ok {
+		return check.status
+	}
+	return ConsistencyStatus{State: JobIdle}
+}
+
+// Start runs a check of opts.Tenant in the background and returns its
+// initial status. It fails with ErrCheckRunning while another check of the
+// tenant runs, and with ErrNotScannable or a validation error before
+// starting.
+func (c *ConsistencyChecker) Start(ctx context.Context, opts ConsistencyOptions) (ConsistencyStatus, error) {
+	if err := opts.validate(c.Clients); err != nil {
+		return ConsistencyStatus{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if check, ok := c.checks[opts.Tenant]; ok && check.status.State == JobRunning {
+		return check.status, ErrCheckRunning
+	}
+
+	now := time.Now()
+	check := &consistencyCheck{status: ConsistencyStatus{
+		State:     JobRunning,
+		StartedAt: &now,
+		Report:    &ConsistencyReport{Mode: opts.Mode, Drift: []Drift{}},
+	}}
+	ctx, check.cancel = context.WithCancel(ctx)
+	c.checks[opts.Tenant] = check
+
+	opts.Progress = func(report ConsistencyReport) {
+		report.Drift = slices.Clone(report.Drift)
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		check.status.Report = &report
+	}
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		report, err := CheckConsistency(ctx, c.DB, c.Clients, opts)
+		c.finish(ctx, check, report, err)
+	}()
+
+	return check.status, nil
+}
+
+// Stop cancels every running check and waits for them to end.
+func (c *ConsistencyChecker) Stop() {
+	c.mu.Lock()
+	for _, check := range c.checks {
+		check.cancel()
+	}
+	c.mu.Unlock()
+
+	c.wg.Wait()
+}
+
+func (c *ConsistencyChecker) finish(ctx context.Context, check *consistencyCheck, report ConsistencyReport, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	check.cancel()
+	now := time.Now()
+	check.status.FinishedAt = &now
+	check.status.Report = &report
+	check.status.State = finalState(err)
+	if err != nil && check.status.State == JobFailed {
+		check.status.Error = err.Error()
+	}
+
+	logging.FromContext(ctx).Info("Cache consistency checked", "state", check.status.State,
+		"mode", report.Mode, "scanned", report.Scanned, "drift", len(report.Drift),
+		"repaired", report.Repaired, "evicted", report.Evicted, "truncated", report.Truncated)
+}
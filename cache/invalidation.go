@@ -0,0 +1,40 @@
+package cache
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+)
+
+// invalidationChannel carries changed keys between replicas as
+// "<origin> <key>".
+const invalidationChannel = "cache:invalidate"
+
+// publishInvalidation tells the other replicas to drop key from their local
+// cache.
+func (r *Redis) publishInvalidation(ctx context.Context, key string) error {
+	if r.local == nil {
+		return nil
+	}
+	return r.client.Publish(ctx, invalidationChannel, r.origin+" "+key).Err()
+}
+
+// listenForInvalidations evicts keys changed by other replicas. The
+// subscription reconnects by itself; invalidations sent while it is down are
+// lost, which LocalTTL bounds.
+func (r *Redis) listenForInvalidations() {
+	r.pubsub = r.client.Subscribe(context.Background(), invalidationChannel)
+
+	go func() {
+		for msg := range r.pubsub.Channel() {
+			origin, key, ok := strings.Cut(msg.Payload, " ")
+			if !ok {
+				slog.Warn("Malformed cache invalidation", "payload", msg.Payload)
+				continue
+			}
+			if origin != r.origin {
+				r.local.Delete(key)
+			}
+		}
+	}()
+}
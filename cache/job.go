@@ -0,0 +1,27 @@
+package cache
+
+import (
+	"context"
+	"errors"
+)
+
+// States of background jobs such as warm-ups and consistency checks.
+const (
+	JobIdle     = "idle"
+	JobRunning  = "running"
+	JobDone     = "done"
+	JobFailed   = "failed"
+	JobCanceled = "canceled"
+)
+
+// finalState returns the state of a job that ended with err.
+func finalState(err error) string {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return JobCanceled
+	case err != nil:
+		return JobFailed
+	default:
+		return JobDone
+	}
+}
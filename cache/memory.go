@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"context"
+	"path"
+	"time"
+)
+
+// Memory keeps entries in process, bounded in number. Every replica has its
+// own, so it suits single-instance deployments and tests.
+type Memory struct {
+	lru *LRU[[]byte]
+}
+
+// NewMemory holds up to size entries.
+func NewMemory(size int) *Memory {
+	return &Memory{lru: NewLRU[[]byte](size, 0)}
+}
+
+func (m *Memory) Get(_ context.Context, key string) ([]byte, error) {
+	value, ok := m.lru.Get(key)
+	if !ok {
+		return nil, ErrMiss
+	}
+	return value, nil
+}
+
+func (m *Memory) MGet(_ context.Context, keys ...string) ([][]byte, error) {
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		values[i], _ = m.lru.Get(key)
+	}
+	return values, nil
+}
+
+func (m *Memory) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	m.lru.Set(key, value, ttl)
+	return nil
+}
+
+func (m *Memory) SetMany(_ context.Context, items ...Item) error {
+	for _, item := range items {
+		m.lru.Set(item.Key, item.Value, item.TTL)
+	}
+	return nil
+}
+
+func (m *Memory) Delete(_ context.Context, keys ...string) error {
+	for _, key := range keys {
+		m.lru.Delete(key)
+	}
+	return nil
+}
+
+func (m *Memory) Scan(ctx context.Context, match string, count int, fn func(keys []string) error) error {
+	var batch []string
+	for _, key := range m.lru.Keys() {
+		if ok, _ := path.Match(match, key); !ok {
+			continue
+		}
+		batch = append(batch, key)
+		if len(batch) >= count {
+			if err := fn(batch); err != nil {
+				return err
+			}
+			batch = nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	if len(batch) > 0 {
+		return fn(batch)
+	}
+	return nil
+}
+
+func (m *Memory) Close() error {
+	m.lru.Purge()
+	return nil
+}
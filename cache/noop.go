@@ -0,0 +1,25 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Noop caches nothing: every lookup misses.
+type Noop struct{}
+
+func (Noop) Get(context.Context, string) ([]byte, error) { return nil, ErrMiss }
+
+func (Noop) MGet(_ context.Context, keys ...string) ([][]byte, error) {
+	return make([][]byte, len(keys)), nil
+}
+
+func (Noop) Set(context.Context, string, []byte, time.Duration) error { return nil }
+
+func (Noop) SetMany(context.Context, ...Item) error { return nil }
+
+func (Noop) Delete(context.Context, ...string) error { return nil }
+
+func (Noop) Scan(context.Context, string, int, func([]string) error) error { return nil }
+
+func (Noop) Close() error { return nil }
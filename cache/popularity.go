@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"sort"
+	"sync"
+)
+
+// popularityTrackSize bounds the clients whose lookups are counted.
+const popularityTrackSize = 10000
+
+// Popularity counts lookups per client in process, so a warm-up can start
+// with the clients most in demand. When more than size clients are tracked
+// every count is halved and those reaching zero are dropped, which also
+// lets old demand fade.
+type Popularity struct {
+	mu     sync.Mutex
+	size   int
+	counts map[string]int64
+}
+
+func NewPopularity(size int) *Popularity {
+	return &Popularity{size: size, counts: make(map[string]int64)}
+}
+
+// Hit counts a lookup of a client.
+func (p *Popularity) Hit(tenant, slug string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.counts[clientKey(tenant, slug)]++
+	for len(p.counts) > p.size {
+		for key, n := range p.counts {
+			if n /= 2; n == 0 {
+				delete(p.counts, key)
+			} else {
+				p.counts[key] = n
+			}
+		}
+	}
+}
+
+// PopularClient is a client identified by tenant and slug.
+type PopularClient struct {
+	Tenant string
+	Slug   string
+}
+
+// Top returns up to n of the most looked up clients, most popular first,
+// for one tenant or, when tenant is empty, for all of them.
+func (p *Popularity) Top(tenant string, n int) []PopularClient {
+	p.mu.Lock()
+	type counted struct {
+		client PopularClient
+		count  int64
+	}
+	all := make([]counted, 0, len(p.counts))
+	for key, count := range p.counts {
+		t, slug, ok := parseClientKey(key)
+		if ok && (tenant == "" || t == tenant) {
+			all = append(all, counted{PopularClient{t, slug}, count})
+		}
+	}
+	p.mu.Unlock()
+
+	sort.Slice(all, func(i, j int) bool { return all[i].count > all[j].count })
+	if len(all) > n {
+		all = all[:n]
+	}
+
+	top := make([]PopularClient, len(all))
+	for i, c := range all {
+		top[i] = c.client
+	}
+	return top
+}
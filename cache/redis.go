@@ -0,0 +1,184 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/farellandr/fullstack2024-test/metrics"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// Redis stores entries in Redis, shared by every replica. With a local size
+// it also keeps recently used entries in process, and replicas tell each
+// other through pub/sub which keys to drop when they change.
+type Redis struct {
+	client *redis.Client
+
+	// local keeps recently read entries in process; nil when disabled.
+	local *LRU[[]byte]
+	// origin tells this replica's invalidation messages from the others'.
+	origin string
+	pubsub *redis.PubSub
+}
+
+// NewRedis caches in client, in front of which up to localSize entries are
+// kept in process for at most localTTL; a zero localSize disables that.
+func NewRedis(client *redis.Client, localSize int, localTTL time.Duration) *Redis {
+	r := &Redis{
+		client: client,
+		origin: uuid.NewString(),
+	}
+	if localSize > 0 {
+		r.local = NewLRU[[]byte](localSize, localTTL)
+		r.listenForInvalidations()
+	}
+	return r
+}
+
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, error) {
+	if r.local != nil {
+		if value, ok := r.local.Get(key); ok {
+			metrics.CacheResult("local", metrics.CacheHit)
+			return value, nil
+		}
+		metrics.CacheResult("local", metrics.CacheMiss)
+	}
+
+	if r.local == nil {
+		value, err := r.client.Get(ctx, key).Bytes()
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrMiss
+		}
+		return value, err
+	}
+
+	// The local copy must not outlive the entry, so its remaining TTL is
+	// read in the same round trip.
+	var get *redis.StringCmd
+	var pttl *redis.DurationCmd
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, key)
+		pttl = pipe.PTTL(ctx, key)
+		return nil
+	})
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrMiss
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	value, _ := get.Bytes()
+	switch ttl := pttl.Val(); {
+	case ttl > 0:
+		r.local.Set(key, value, ttl)
+	case ttl == -1:
+		// No expiry in Redis; LocalTTL bounds the copy.
+		r.local.Set(key, value, 0)
+	}
+	return value, nil
+}
+
+func (r *Redis) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	values := make([][]byte, len(keys))
+	if len(keys) == 0 {
+		return values, nil
+	}
+
+	res, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, v := range res {
+		if s, ok := v.(string); ok {
+			values[i] = []byte(s)
+		}
+	}
+	return values, nil
+}
+
+// Set writes through to Redis and drops key from the local cache of every
+// other replica.
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := r.client.Set(ctx, key, value, ttl).Err(); err != nil {
+		return err
+	}
+
+	if r.local != nil {
+		r.local.Set(key, value, ttl)
+	}
+	return r.publishInvalidation(ctx, key)
+}
+
+// SetMany writes every item, and the matching invalidations, in one
+// pipeline.
+func (r *Redis) SetMany(ctx context.Context, items ...Item) error {
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, item := range items {
+			pipe.Set(ctx, item.Key, item.Value, item.TTL)
+			if r.local != nil {
+				pipe.Publish(ctx, invalidationChannel, r.origin+" "+item.Key)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if r.local != nil {
+		for _, item := range items {
+			r.local.Set(item.Key, item.Value, item.TTL)
+		}
+	}
+	return nil
+}
+
+func (r *Redis) Delete(ctx context.Context, keys ...string) error {
+	if r.local != nil {
+		for _, key := range keys {
+			r.local.Delete(key)
+		}
+	}
+
+	if err := r.client.Del(ctx, keys...).Err(); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := r.publishInvalidation(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Redis) Scan(ctx context.Context, match string, count int, fn func(keys []string) error) error {
+	var cursor uint64
+	for {
+		keys, next, err := r.client.Scan(ctx, cursor, match, int64(count)).Result()
+		if err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return err
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// Close stops listening for invalidations. The Redis client belongs to the
+// caller and stays open.
+func (r *Redis) Close() error {
+	if r.pubsub != nil {
+		return r.pubsub.Close()
+	}
+	return nil
+}
@@ -0,0 +1,304 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/farellandr/fullstack2024-test/logging"
+	"github.com/farellandr/fullstack2024-test/models"
+	"github.com/farellandr/fullstack2024-test/tenancy"
+	"golang.org/x/sync/errgroup"
+	"gorm.io/gorm"
+)
+
+// Warm-up strategies.
+const (
+	// WarmRecent preloads the most recently updated clients.
+	WarmRecent = "recent"
+	// WarmPopular preloads the clients looked up most by this replica,
+	// topped up with recently updated ones.
+	WarmPopular = "popular"
+)
+
+// ErrWarmUpRunning is returned when a warm-up is started for a tenant, or
+// for all of them, while the previous one still runs.
+var ErrWarmUpRunning = errors.New("a cache warm-up is already running")
+
+// WarmUpOptions control a warm-up.
+type WarmUpOptions struct {
+	Strategy string
+	// Tenant restricts the warm-up to one tenant; empty warms them all.
+	Tenant string
+	// Count is the number of clients preloaded.
+	Count int
+	// Concurrency caps the batches loaded at once.
+	Concurrency int
+	// BatchSize is the number of clients loaded by one query and written by
+	// one pipeline.
+	BatchSize int
+}
+
+// WarmUpProgress describes the running or last warm-up.
+type WarmUpProgress struct {
+	State      string
+	Strategy   string     `json:",omitempty"`
+	Tenant     string     `json:",omitempty"`
+	Total      int        `json:",omitempty"`
+	Loaded     int        `json:",omitempty"`
+	Failed     int        `json:",omitempty"`
+	StartedAt  *time.Time `json:",omitempty"`
+	FinishedAt *time.Time `json:",omitempty"`
+	Error      string     `json:",omitempty"`
+}
+
+// Warmer preloads clients into the cache in the background, one warm-up at
+// a time per tenant. A warm-up of all tenants, such as the one at startup,
+// is tracked on its own and neither blocks nor shows in tenant warm-ups.
+type Warmer struct {
+	DB      *gorm.DB
+	Clients *Clients
+
+	mu      sync.Mutex
+	warmUps map[string]*warmUp
+	wg      sync.WaitGroup
+}
+
+type warmUp struct {
+	progress WarmUpProgress
+	cancel   context.CancelFunc
+}
+
+func NewWarmer(db *gorm.DB, clients *Clients) *Warmer {
+	return &Warmer{
+		DB:      db,
+		Clients: clients,
+		warmUps: make(map[string]*warmUp),
+	}
+}
+
+// Progress returns the running or last warm-up of tenant, or of all tenants
+// when tenant is empty.
+func (w *Warmer) Progress(tenant string) WarmUpProgress {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if warm, ok := w.warmUps[tenant]; ok {
+		return warm.progress
+	}
+	return WarmUpProgress{State: JobIdle}
+}
+
+// Start runs a warm-up of opts.Tenant in the background and returns its
+// initial progress. It fails with ErrWarmUpRunning while another warm-up of
+// the same tenant, or of all tenants for an empty one, runs.
+func (w *Warmer) Start(ctx context.Context, opts WarmUpOptions) (WarmUpProgress, error) {
+	switch opts.Strategy {
+	case WarmRecent, WarmPopular:
+	default:
+		return WarmUpProgress{}, fmt.Errorf("unknown warm-up strategy %q", opts.Strategy)
+	}
+	if opts.Count <= 0 || opts.Concurrency <= 0 || opts.BatchSize <= 0 {
+		return WarmUpProgress{}, errors.New("warm-up count, concurrency and batch size must be positive")
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if warm, ok := w.warmUps[opts.Tenant]; ok && warm.progress.State == JobRunning {
+		return warm.progress, ErrWarmUpRunning
+	}
+
+	now := time.Now()
+	warm := &warmUp{progress: WarmUpProgress{State: JobRunning, Strategy: opts.Strategy, Tenant: opts.Tenant, StartedAt: &now}}
+	ctx, warm.cancel = context.WithCancel(ctx)
+	w.warmUps[opts.Tenant] = warm
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.finish(ctx, warm, w.run(ctx, warm, opts))
+	}()
+
+	return warm.progress, nil
+}
+
+// Stop cancels every running warm-up and waits for them to end.
+func (w *Warmer) Stop() {
+	w.mu.Lock()
+	for _, warm := range w.warmUps {
+		warm.cancel()
+	}
+	w.mu.Unlock()
+
+	w.wg.Wait()
+}
+
+func (w *Warmer) finish(ctx context.Context, warm *warmUp, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	warm.cancel()
+	now := time.Now()
+	p := &warm.progress
+	p.FinishedAt = &now
+	p.State = finalState(err)
+	if err != nil && p.State == JobFailed {
+		p.Error = err.Error()
+	}
+
+	logging.FromContext(ctx).Info("Cache warm-up finished", "state", p.State, "tenant", p.Tenant, "loaded", p.Loaded,
+		"failed", p.Failed, "total", p.Total, "duration", now.Sub(*p.StartedAt))
+}
+
+// warmTarget is a client picked for a warm-up.
+type warmTarget struct {
+	ID       uint
+	TenantID string
+	Slug     string
+}
+
+func (w *Warmer) run(ctx context.Context, warm *warmUp, opts WarmUpOptions) error {
+	db := w.DB.WithContext(tenancy.WithoutTenant(ctx))
+
+	targets, err := w.targets(db, opts)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	warm.progress.Total = len(targets)
+	w.mu.Unlock()
+	log := logging.FromContext(ctx)
+	log.Info("Cache warm-up started", "strategy", opts.Strategy, "tenant", opts.Tenant, "clients", len(targets))
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(opts.Concurrency)
+
+	var lastErr error
+	step := max(len(targets)/10, 1)
+	for start := 0; start < len(targets); start += opts.BatchSize {
+		batch := targets[start:min(start+opts.BatchSize, len(targets))]
+		if gctx.Err() != nil {
+			break
+		}
+
+		g.Go(func() error {
+			loaded, err := w.load(gctx, db, batch)
+
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			p := &warm.progress
+			before := p.Loaded + p.Failed
+			p.Loaded += loaded
+			if err != nil {
+				// A failed batch does not stop the others; the clients in it
+				// are simply loaded on demand later.
+				p.Failed += len(batch) - loaded
+				lastErr = err
+				log.Warn("Cache warm-up batch failed", "clients", len(batch), "error", err)
+			}
+			if done := p.Loaded + p.Failed; done/step > before/step {
+				log.Info("Cache warm-up progress", "tenant", opts.Tenant, "loaded", p.Loaded, "failed", p.Failed, "total", p.Total)
+			}
+			return gctx.Err()
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if warm.progress.Loaded == 0 && lastErr != nil {
+		return lastErr
+	}
+	return nil
+}
+
+// targets picks the clients to preload.
+func (w *Warmer) targets(db *gorm.DB, opts WarmUpOptions) ([]warmTarget, error) {
+	var targets []warmTarget
+	seen := make(map[uint]bool)
+
+	if opts.Strategy == WarmPopular {
+		byTenant := make(map[string][]string)
+		order := make(map[string]int)
+		for i, p := range w.Clients.Popularity.Top(opts.Tenant, opts.Count) {
+			byTenant[p.Tenant] = append(byTenant[p.Tenant], p.Slug)
+			order[clientKey(p.Tenant, p.Slug)] = i
+		}
+
+		for tenant, slugs := range byTenant {
+			var found []warmTarget
+			err := db.Model(&models.Client{}).Select("id, tenant_id, slug").
+				Where("tenant_id = ? AND slug IN ?", tenant, slugs).
+				Scan(&found).Error
+			if err != nil {
+				return nil, err
+			}
+			targets = append(targets, found...)
+		}
+		sortTargets(targets, order)
+		for _, t := range targets {
+			seen[t.ID] = true
+		}
+	}
+
+	if len(targets) >= opts.Count {
+		return targets[:opts.Count], nil
+	}
+
+	query := db.Model(&models.Client{}).Select("id, tenant_id, slug").
+		Order("updated_at IS NULL, updated_at DESC, id DESC").
+		Limit(opts.Count + len(seen))
+	if opts.Tenant != "" {
+		query = query.Where("tenant_id = ?", opts.Tenant)
+	}
+
+	var recent []warmTarget
+	if err := query.Scan(&recent).Error; err != nil {
+		return nil, err
+	}
+	for _, t := range recent {
+		if len(targets) == opts.Count {
+			break
+		}
+		if !seen[t.ID] {
+			targets = append(targets, t)
+		}
+	}
+
+	return targets, nil
+}
+
+// load reads a batch of clients with their tags and writes them to the cache
+// in one round trip, returning how many were cached.
+func (w *Warmer) load(ctx context.Context, db *gorm.DB, batch []warmTarget) (int, error) {
+	ids := make([]uint, len(batch))
+	for i, t := range batch {
+		ids[i] = t.ID
+	}
+
+	var clients []models.Client
+	if err := db.Preload("Tags").Where("id IN ?", ids).Find(&clients).Error; err != nil {
+		return 0, err
+	}
+
+	if err := w.Clients.FillMany(ctx, clients); err != nil {
+		return 0, err
+	}
+	return len(clients), nil
+}
+
+func sortTargets(targets []warmTarget, order map[string]int) {
+	sort.Slice(targets, func(i, j int) bool {
+		return order[clientKey(targets[i].TenantID, targets[i].Slug)] < order[clientKey(targets[j].TenantID, targets[j].Slug)]
+	})
+}
@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/farellandr/fullstack2024-test/config"
+	"github.com/farellandr/fullstack2024-test/models"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// blockingCache holds every SetMany until release is closed, keeping
+// warm-ups running.
+type blockingCache struct {
+	*Memory
+	release chan struct{}
+}
+
+func (c blockingCache) SetMany(ctx context.Context, items ...Item) error {
+	select {
+	case <-c.release:
+		return c.Memory.SetMany(ctx, items...)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestWarmUpsAreTrackedPerTenant(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err := models.Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	for i, tenant := range []string{"alpha", "alpha", "beta"} {
+		client := models.Client{TenantID: tenant, Name: "Client", Slug: fmt.Sprintf("client-%d", i), ClientPrefix: fmt.Sprintf("C%03d", i)}
+		if err := db.Create(&client).Error; err != nil {
+			t.Fatalf("create client: %v", err)
+		}
+	}
+
+	backend := blockingCache{Memory: NewMemory(100), release: make(chan struct{})}
+	warmer := NewWarmer(db, NewClients(backend, config.CacheConfig{TTL: time.Minute}))
+	defer warmer.Stop()
+
+	opts := WarmUpOptions{Strategy: WarmRecent, Count: 10, Concurrency: 1, BatchSize: 10}
+	if _, err := warmer.Start(context.Background(), opts); err != nil {
+		t.Fatalf("start warm-up of all tenants: %v", err)
+	}
+	if _, err := warmer.Start(context.Background(), opts); !errors.Is(err, ErrWarmUpRunning) {
+		t.Errorf("second warm-up of all tenants: got %v, want ErrWarmUpRunning", err)
+	}
+
+	if progress := warmer.Progress("alpha"); progress.State != JobIdle {
+		t.Errorf("tenant sees the warm-up of all tenants: %+v", progress)
+	}
+
+	alpha := opts
+	alpha.Tenant = "alpha"
+	if _, err := warmer.Start(context.Background(), alpha); err != nil {
+		t.Fatalf("tenant warm-up during the warm-up of all tenants: %v", err)
+	}
+	if _, err := warmer.Start(context.Background(), alpha); !errors.Is(err, ErrWarmUpRunning) {
+		t.Errorf("second warm-up of a tenant: got %v, want ErrWarmUpRunning", err)
+	}
+	if progress := warmer.Progress("beta"); progress.State != JobIdle {
+		t.Errorf("beta sees the warm-up of alpha: %+v", progress)
+	}
+
+	close(backend.release)
+	for _, tc := range []struct {
+		tenant string
+		loaded int
+	}{
+		{"", 3},
+		{"alpha", 2},
+	} {
+		deadline := time.Now().Add(5 * time.Second)
+		progress := warmer.Progress(tc.tenant)
+		for progress.State == JobRunning && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+			progress = warmer.Progress(tc.tenant)
+		}
+		if progress.State != JobDone || progress.Loaded != tc.loaded {
+			t.Errorf("warm-up of %q: state %s, loaded %d, want done with %d", tc.tenant, progress.State, progress.Loaded, tc.loaded)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Command backfill-cities canonicalises the City column of existing my_client
+// rows against the gazetteer, filling in Province and CountryCode where they
+// are missing. Rows whose city cannot be resolved are reported and left alone.
+//
+//	go run ./cmd/backfill-cities -dry-run
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"github.com/farellandr/fullstack2024-test/cache"
+	"github.com/farellandr/fullstack2024-test/config"
+	"github.com/farellandr/fullstack2024-test/gazetteer"
+	"github.com/farellandr/fullstack2024-test/models"
+	"github.com/farellandr/fullstack2024-test/utils"
+	"github.com/joho/godotenv"
+	"gorm.io/gorm"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report the changes without writing them")
+	batchSize := flag.Int("batch-size", 500, "number of rows loaded per batch")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println(".env file not found")
+	}
+
+	db, err := config.InitDB()
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	clientCache := cache.NewClients(cache.Noop{}, config.CacheConfig{})
+	if !*dryRun {
+		cacheConfig, err := config.LoadCacheConfig()
+		if err != nil {
+			log.Fatalf("Invalid cache configuration: %v", err)
+		}
+		redisConfig, err := config.LoadRedisConfig()
+		if err != nil {
+			log.Fatalf("Invalid Redis configuration: %v", err)
+		}
+		backend, err := cache.New(cacheConfig, utils.InitRedis(redisConfig).Client)
+		if err != nil {
+			log.Fatalf("Failed to initialise cache: %v", err)
+		}
+		clientCache = cache.NewClients(backend, cacheConfig)
+	}
+
+	ctx := context.Background()
+	g := gazetteer.Default()
+	var scanned, updated, unresolved int
+
+	var batch []models.Client
+	result := db.Unscoped().Where("city <> ''").FindInBatches(&batch, *batchSize, func(tx *gorm.DB, _ int) error {
+		for _, client := range batch {
+			scanned++
+
+			city, ok := g.Lookup(client.City, client.CountryCode)
+			if !ok {
+				unresolved++
+				log.Printf("unresolved: client %d (%s) city %q, suggestions %v",
+					client.ID, client.Slug, client.City, g.Suggest(client.City, client.CountryCode, 3))
+				continue
+			}
+
+			changes := map[string]interface{}{}
+			if client.City != city.Name {
+				changes["city"] = city.Name
+				client.City = city.Name
+			}
+			if client.Province == "" {
+				changes["province"] = city.Province
+				client.Province = city.Province
+			}
+			if client.CountryCode == "" {
+				changes["country_code"] = city.CountryCode
+				client.CountryCode = city.CountryCode
+			}
+			if len(changes) == 0 {
+				continue
+			}
+
+			// Only rows written through the structured address fields have an
+			// Address derived from them; free-text legacy addresses are kept.
+			if client.Street != "" || client.District != "" || client.PostalCode != "" {
+				changes["address"] = client.FormatAddress()
+			}
+
+			updated++
+			log.Printf("client %d (%s): %v", client.ID, client.Slug, changes)
+			if *dryRun {
+				continue
+			}
+
+			if err := db.Unscoped().Model(&models.Client{}).Where("id = ?", client.ID).Updates(changes).Error; err != nil {
+				return err
+			}
+
+			if err := clientCache.Delete(ctx, client.TenantID, client.Slug); err != nil {
+				log.Printf("Warning: Failed to evict client from the cache: %v", err)
+			}
+		}
+		return nil
+	})
+	if result.Error != nil {
+		log.Fatalf("Backfill failed after %d rows: %v", scanned, result.Error)
+	}
+
+	log.Printf("Scanned %d clients: %d updated, %d unresolved (dry run: %t)", scanned, updated, unresolved, *dryRun)
+}
@@ -0,0 +1,82 @@
+// Command check-cache compares cached clients with their my_client rows,
+// soft-deleted ones included, and reports entries that drifted. With
+// -mode=repair entries of live clients are rewritten from the database and
+// the others evicted; with -mode=evict every drifted entry is evicted. Only
+// the redis cache backend can be checked.
+//
+//	go run ./cmd/check-cache -mode=report -rate=200
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os/signal"
+	"syscall"
+
+	"github.com/farellandr/fullstack2024-test/cache"
+	"github.com/farellandr/fullstack2024-test/config"
+	"github.com/farellandr/fullstack2024-test/utils"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	mode := flag.String("mode", cache.ModeReport, "report, repair or evict")
+	tenant := flag.String("tenant", "", "only check this tenant")
+	rate := flag.Int("rate", 200, "maximum entries checked per second")
+	limit := flag.Int("limit", 0, "stop after this many entries (0 checks all)")
+	batchSize := flag.Int("batch-size", 100, "number of entries fetched per batch")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println(".env file not found")
+	}
+
+	cacheConfig, err := config.LoadCacheConfig()
+	if err != nil {
+		log.Fatalf("Invalid cache configuration: %v", err)
+	}
+	// Any other backend would be a new, empty cache private to this process,
+	// not the one the API serves from.
+	if cacheConfig.Backend != config.CacheBackendRedis {
+		log.Fatalf("CACHE_BACKEND=%s cannot be checked from outside the API; only the redis backend is shared", cacheConfig.Backend)
+	}
+
+	db, err := config.InitDB()
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	redisConfig, err := config.LoadRedisConfig()
+	if err != nil {
+		log.Fatalf("Invalid Redis configuration: %v", err)
+	}
+	backend, err := cache.New(cacheConfig, utils.InitRedis(redisConfig).Client)
+	if err != nil {
+		log.Fatalf("Failed to initialise cache: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	report, err := cache.CheckConsistency(ctx, db, cache.NewClients(backend, cacheConfig), cache.ConsistencyOptions{
+		Mode:      *mode,
+		Tenant:    *tenant,
+		Rate:      *rate,
+		Limit:     *limit,
+		BatchSize: *batchSize,
+	})
+	for _, d := range report.Drift {
+		if d.Error != "" {
+			log.Printf("%s: %s, %s: %s", d.Key, d.Kind, d.Action, d.Error)
+		} else {
+			log.Printf("%s: %s, %s", d.Key, d.Kind, d.Action)
+		}
+	}
+	if err != nil {
+		log.Fatalf("Check failed after %d entries: %v", report.Scanned, err)
+	}
+
+	log.Printf("Checked %d entries: %d consistent, %d drifted, %d repaired, %d evicted (mode: %s, truncated: %t)",
+		report.Scanned, report.Consistent, len(report.Drift), report.Repaired, report.Evicted, report.Mode, report.Truncated)
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AuthConfig configures bearer-token authentication of the API.
+type AuthConfig struct {
+	// Algorithm is the only accepted signing algorithm: HS256 or RS256.
+	Algorithm string
+	// Secret is the shared HMAC key used with HS256.
+	Secret string
+	// JWKSFile is a local JSON Web Key Set holding the RSA public keys used with RS256.
+	JWKSFile string
+	// Issuer and Audiences, when set, must match the iss and aud claims.
+	Issuer    string
+	Audiences []string
+	// ClockSkew is the leeway allowed when checking exp, nbf and iat.
+	ClockSkew time.Duration
+	// TenantClaim names the claim binding a user token to a tenant.
+	TenantClaim string
+	// AdminSubjects are assigned the admin role on startup, so that a fresh
+	// database has someone able to grant roles.
+	AdminSubjects []string
+}
+
+func LoadAuthConfig() (AuthConfig, error) {
+	cfg := AuthConfig{
+		Algorithm: strings.ToUpper(getEnv("JWT_ALGORITHM", "HS256")),
+		Secret:    getEnv("JWT_SECRET", ""),
+		JWKSFile:  getEnv("JWT_JWKS_FILE", ""),
+		Issuer:    getEnv("JWT_ISSUER", ""),
+		Audiences: getEnvList("JWT_AUDIENCE"),
+
+		TenantClaim: getEnv("JWT_TENANT_CLAIM", "tenant_id"),
+
+		AdminSubjects: getEnvList("AUTH_ADMIN_SUBJECTS"),
+	}
+
+	skew, err := getEnvDuration("JWT_CLOCK_SKEW", 30*time.Second)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.ClockSkew = skew
+
+	switch cfg.Algorithm {
+	case "HS256":
+		if cfg.Secret == "" {
+			return cfg, errors.New("JWT_SECRET is required for HS256")
+		}
+	case "RS256":
+		if cfg.JWKSFile == "" {
+			return cfg, errors.New("JWT_JWKS_FILE is required for RS256")
+		}
+	default:
+		return cfg, fmt.Errorf("unsupported JWT_ALGORITHM %q: use HS256 or RS256", cfg.Algorithm)
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Cache backends selectable with CACHE_BACKEND.
+const (
+	CacheBackendRedis  = "redis"
+	CacheBackendMemory = "memory"
+	CacheBackendNone   = "none"
+)
+
+// CacheConfig selects the cache backend and controls how long cached
+// clients live.
+type CacheConfig struct {
+	// Backend is redis (shared by replicas), memory (per process, sized by
+	// LocalSize) or none.
+	Backend string
+	// TTL is how long an entry is fresh. Each write varies it by up to
+	// ±TTLJitter (a fraction of TTL) so entries written together do not
+	// expire together.
+	TTL       time.Duration
+	TTLJitter float64
+	// NegativeTTL is how long an unknown slug is remembered as such.
+	NegativeTTL time.Duration
+	// StaleTTL keeps entries past their TTL for this long, served while a
+	// refresh runs in the background. Zero disables stale-while-revalidate.
+	StaleTTL time.Duration
+	// LocalSize bounds the per-replica in-memory cache in front of Redis;
+	// zero disables it. LocalTTL bounds the age of its entries. The memory
+	// backend holds LocalSize entries without an age bound.
+	LocalSize int
+	LocalTTL  time.Duration
+	// ListTTL bounds how long a cached client list or search page lives;
+	// any client change invalidates it sooner. Zero disables list caching.
+	ListTTL time.Duration
+	// WarmUpCount is the number of clients preloaded at startup; zero
+	// disables the startup warm-up. WarmUpConcurrency caps the batches of
+	// WarmUpBatchSize clients loaded at once by any warm-up.
+	WarmUpCount       int
+	WarmUpConcurrency int
+	WarmUpBatchSize   int
+}
+
+func LoadCacheConfig() (CacheConfig, error) {
+	var cfg CacheConfig
+	var err error
+
+	cfg.Backend = getEnv("CACHE_BACKEND", CacheBackendRedis)
+	switch cfg.Backend {
+	case CacheBackendRedis, CacheBackendMemory, CacheBackendNone:
+	default:
+		return cfg, fmt.Errorf("CACHE_BACKEND must be redis, memory or none")
+	}
+
+	if cfg.TTL, err = getEnvDuration("CACHE_TTL", 10*time.Minute); err != nil {
+		return cfg, err
+	}
+	if cfg.NegativeTTL, err = getEnvDuration("CACHE_NEGATIVE_TTL", 30*time.Second); err != nil {
+		return cfg, err
+	}
+	if cfg.StaleTTL, err = getEnvDuration("CACHE_STALE_TTL", 0); err != nil {
+		return cfg, err
+	}
+
+	if cfg.ListTTL, err = getEnvDuration("CACHE_LIST_TTL", time.Minute); err != nil {
+		return cfg, err
+	}
+
+	if cfg.LocalTTL, err = getEnvDuration("LOCAL_CACHE_TTL", 30*time.Second); err != nil {
+		return cfg, err
+	}
+
+	size, err := strconv.Atoi(getEnv("LOCAL_CACHE_SIZE", "10000"))
+	if err != nil || size < 0 {
+		return cfg, fmt.Errorf("LOCAL_CACHE_SIZE must be a non-negative integer")
+	}
+	cfg.LocalSize = size
+	if size > 0 && cfg.LocalTTL <= 0 {
+		return cfg, fmt.Errorf("LOCAL_CACHE_TTL must be positive")
+	}
+	if size == 0 && cfg.Backend == CacheBackendMemory {
+		return cfg, fmt.Errorf("LOCAL_CACHE_SIZE must be positive for the memory cache backend")
+	}
+
+	jitter, err := strconv.ParseFloat(getEnv("CACHE_TTL_JITTER", "0.1"), 64)
+	if err != nil || jitter < 0 || jitter >= 1 {
+		return cfg, fmt.Errorf("CACHE_TTL_JITTER must be a fraction between 0 and 1")
+	}
+	cfg.TTLJitter = jitter
+
+	count, err := strconv.Atoi(getEnv("CACHE_WARMUP_COUNT", "1000"))
+	if err != nil || count < 0 {
+		return cfg, fmt.Errorf("CACHE_WARMUP_COUNT must be a non-negative integer")
+	}
+	cfg.WarmUpCount = count
+
+	concurrency, err := getEnvInt("CACHE_WARMUP_CONCURRENCY", 4)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.WarmUpConcurrency = int(concurrency)
+
+	batchSize, err := getEnvInt("CACHE_WARMUP_BATCH_SIZE", 100)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.WarmUpBatchSize = int(batchSize)
+
+	if cfg.TTL <= 0 {
+		return cfg, fmt.Errorf("CACHE_TTL must be positive")
+	}
+
+	return cfg, nil
+}
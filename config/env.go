@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func getEnv(key, fallback string) string {
+	if value, exists := os.LookupEnv(key); exists {
+		return value
+	}
+	return fallback
+}
+
+func getEnvDuration(key string, fallback time.Duration) (time.Duration, error) {
+	value := getEnv(key, "")
+	if value == "" {
+		return fallback, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: invalid duration %q", key, value)
+	}
+	return d, nil
+}
+
+// getEnvList splits a comma-separated variable, dropping empty items.
+func getEnvList(key string) []string {
+	var items []string
+	for _, item := range strings.Split(getEnv(key, ""), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// getEnvRoutes reads a list of "METHOD /route=value" overrides, keyed by the
+// method and route with whitespace collapsed.
+func getEnvRoutes(key string) (map[string]string, error) {
+	routes := make(map[string]string)
+	for _, entry := range getEnvList(key) {
+		route, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s: %q is not ROUTE=VALUE", key, entry)
+		}
+		routes[strings.Join(strings.Fields(route), " ")] = strings.TrimSpace(value)
+	}
+	return routes, nil
+}
+
+func getEnvInt(key string, fallback int64) (int64, error) {
+	value := getEnv(key, "")
+	if value == "" {
+		return fallback, nil
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%s: invalid positive integer %q", key, value)
+	}
+	return n, nil
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// Log formats selectable with LOG_FORMAT.
+const (
+	LogFormatJSON = "json"
+	LogFormatText = "text"
+)
+
+type LoggingConfig struct {
+	Level  slog.Level
+	Format string
+}
+
+func LoadLoggingConfig() (LoggingConfig, error) {
+	cfg := LoggingConfig{Format: strings.ToLower(getEnv("LOG_FORMAT", LogFormatJSON))}
+
+	if err := cfg.Level.UnmarshalText([]byte(getEnv("LOG_LEVEL", "info"))); err != nil {
+		return cfg, fmt.Errorf("LOG_LEVEL: %w", err)
+	}
+
+	if cfg.Format != LogFormatJSON && cfg.Format != LogFormatText {
+		return cfg, fmt.Errorf("LOG_FORMAT must be json or text, got %q", cfg.Format)
+	}
+
+	return cfg, nil
+}
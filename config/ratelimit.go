@@ -0,0 +1,90 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimit allows Requests per Window, refilled continuously.
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// Disabled reports whether the limit lets every request through.
+func (l RateLimit) Disabled() bool {
+	return l.Requests <= 0
+}
+
+type RateLimitConfig struct {
+	Enabled bool
+	// PerIP is shared by every request from one client IP, checked before
+	// authentication so that guessing credentials is throttled too.
+	PerIP   RateLimit
+	Default RateLimit
+	// Routes overrides Default per "METHOD /full/route/:param".
+	Routes map[string]RateLimit
+}
+
+// LoadRateLimitConfig reads RATE_LIMIT_PER_IP and RATE_LIMIT_DEFAULT
+// ("100/1m") and RATE_LIMIT_ROUTES
+// ("POST /api/v1/clients=10/1m,DELETE /api/v1/clients/:slug=off").
+func LoadRateLimitConfig() (RateLimitConfig, error) {
+	cfg := RateLimitConfig{
+		Enabled: getEnv("RATE_LIMIT_ENABLED", "true") == "true",
+		Routes:  make(map[string]RateLimit),
+	}
+
+	perIP, err := parseRateLimit(getEnv("RATE_LIMIT_PER_IP", "600/1m"))
+	if err != nil {
+		return cfg, fmt.Errorf("RATE_LIMIT_PER_IP: %w", err)
+	}
+	cfg.PerIP = perIP
+
+	def, err := parseRateLimit(getEnv("RATE_LIMIT_DEFAULT", "300/1m"))
+	if err != nil {
+		return cfg, fmt.Errorf("RATE_LIMIT_DEFAULT: %w", err)
+	}
+	cfg.Default = def
+
+	routes, err := getEnvRoutes("RATE_LIMIT_ROUTES")
+	if err != nil {
+		return cfg, err
+	}
+	for route, spec := range routes {
+		limit, err := parseRateLimit(spec)
+		if err != nil {
+			return cfg, fmt.Errorf("RATE_LIMIT_ROUTES: %s: %w", route, err)
+		}
+		cfg.Routes[route] = limit
+	}
+
+	return cfg, nil
+}
+
+// parseRateLimit parses "N/duration", or "off" for no limit.
+func parseRateLimit(spec string) (RateLimit, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "off" {
+		return RateLimit{}, nil
+	}
+
+	count, window, ok := strings.Cut(spec, "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("%q is not N/duration", spec)
+	}
+
+	n, err := strconv.Atoi(count)
+	if err != nil || n < 1 {
+		return RateLimit{}, fmt.Errorf("%q: request count must be a positive integer", spec)
+	}
+
+	d, err := time.ParseDuration(window)
+	if err != nil || d < time.Millisecond {
+		return RateLimit{}, fmt.Errorf("%q: invalid window", spec)
+	}
+
+	return RateLimit{Requests: n, Window: d}, nil
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// RedisConfig configures the Redis connection pool and the circuit breaker
+// in front of it.
+type RedisConfig struct {
+	Addr     string
+	Password string
+	DB       int
+
+	// PoolSize caps open connections; zero keeps the client default of ten
+	// per CPU. MinIdleConns are kept open ahead of demand.
+	PoolSize     int
+	MinIdleConns int
+	// DialTimeout, ReadTimeout and WriteTimeout bound each connection
+	// attempt and command. PoolTimeout bounds the wait for a free
+	// connection when the pool is exhausted.
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	PoolTimeout  time.Duration
+
+	// BreakerFailures consecutive failed commands open the circuit breaker,
+	// which then fails commands immediately for BreakerCooldown before
+	// letting a single probe through.
+	BreakerFailures int
+	BreakerCooldown time.Duration
+}
+
+func LoadRedisConfig() (RedisConfig, error) {
+	cfg := RedisConfig{
+		Addr:     getEnv("REDIS_HOST", "localhost") + ":" + getEnv("REDIS_PORT", "6379"),
+		Password: getEnv("REDIS_PASSWORD", ""),
+	}
+
+	ints := []struct {
+		key      string
+		fallback int
+		dst      *int
+	}{
+		{"REDIS_DB", 0, &cfg.DB},
+		{"REDIS_POOL_SIZE", 0, &cfg.PoolSize},
+		{"REDIS_MIN_IDLE_CONNS", 0, &cfg.MinIdleConns},
+		{"REDIS_BREAKER_FAILURES", 5, &cfg.BreakerFailures},
+	}
+	for _, i := range ints {
+		n, err := strconv.Atoi(getEnv(i.key, strconv.Itoa(i.fallback)))
+		if err != nil || n < 0 {
+			return cfg, fmt.Errorf("%s must be a non-negative integer", i.key)
+		}
+		*i.dst = n
+	}
+	if cfg.BreakerFailures == 0 {
+		return cfg, fmt.Errorf("REDIS_BREAKER_FAILURES must be positive")
+	}
+
+	durations := []struct {
+		key      string
+		fallback time.Duration
+		dst      *time.Duration
+	}{
+		{"REDIS_DIAL_TIMEOUT", 2 * time.Second, &cfg.DialTimeout},
+		{"REDIS_READ_TIMEOUT", time.Second, &cfg.ReadTimeout},
+		{"REDIS_WRITE_TIMEOUT", time.Second, &cfg.WriteTimeout},
+		{"REDIS_POOL_TIMEOUT", 2 * time.Second, &cfg.PoolTimeout},
+		{"REDIS_BREAKER_COOLDOWN", 10 * time.Second, &cfg.BreakerCooldown},
+	}
+	for _, d := range durations {
+		v, err := getEnvDuration(d.key, d.fallback)
+		if err != nil {
+			return cfg, err
+		}
+		if v <= 0 {
+			return cfg, fmt.Errorf("%s must be positive", d.key)
+		}
+		*d.dst = v
+	}
+
+	return cfg, nil
+}
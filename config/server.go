@@ -0,0 +1,72 @@
+package config
+
+import (
+	"time"
+)
+
+// ServerConfig configures the HTTP listener and its shutdown.
+type ServerConfig struct {
+	Port              string
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	// MaxBodyBytes caps request bodies, logo uploads included.
+	MaxBodyBytes int64
+	// ShutdownDelay is how long readiness fails before the listener closes.
+	ShutdownDelay time.Duration
+	// ShutdownTimeout bounds how long in-flight requests may drain after
+	// SIGTERM before connections are closed.
+	ShutdownTimeout time.Duration
+	// HealthCheckTimeout bounds each dependency check of the probes.
+	HealthCheckTimeout time.Duration
+	// TrustedProxies are the addresses or CIDRs whose X-Forwarded-For
+	// header is believed. With none, the client IP is the peer address,
+	// so callers cannot pick the IP they are rate limited by.
+	TrustedProxies []string
+}
+
+func (c ServerConfig) Addr() string {
+	return ":" + c.Port
+}
+
+func LoadServerConfig() (ServerConfig, error) {
+	cfg := ServerConfig{
+		Port:           getEnv("PORT", "3222"),
+		TrustedProxies: getEnvList("TRUSTED_PROXIES"),
+	}
+
+	durations := []struct {
+		key      string
+		fallback time.Duration
+		dst      *time.Duration
+	}{
+		{"SERVER_READ_HEADER_TIMEOUT", 5 * time.Second, &cfg.ReadHeaderTimeout},
+		{"SERVER_READ_TIMEOUT", 30 * time.Second, &cfg.ReadTimeout},
+		{"SERVER_WRITE_TIMEOUT", 60 * time.Second, &cfg.WriteTimeout},
+		{"SERVER_IDLE_TIMEOUT", 120 * time.Second, &cfg.IdleTimeout},
+		{"SERVER_SHUTDOWN_DELAY", 0, &cfg.ShutdownDelay},
+		{"SERVER_SHUTDOWN_TIMEOUT", 30 * time.Second, &cfg.ShutdownTimeout},
+		{"HEALTH_CHECK_TIMEOUT", 2 * time.Second, &cfg.HealthCheckTimeout},
+	}
+	for _, d := range durations {
+		v, err := getEnvDuration(d.key, d.fallback)
+		if err != nil {
+			return cfg, err
+		}
+		*d.dst = v
+	}
+
+	headerBytes, err := getEnvInt("SERVER_MAX_HEADER_BYTES", 1<<20)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.MaxHeaderBytes = int(headerBytes)
+
+	if cfg.MaxBodyBytes, err = getEnvInt("SERVER_MAX_BODY_BYTES", 10<<20); err != nil {
+		return cfg, err
+	}
+
+	return cfg, nil
+}
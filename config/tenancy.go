@@ -0,0 +1,22 @@
+package config
+
+import (
+	"strings"
+)
+
+// TenancyConfig controls how the tenant of a request is resolved.
+type TenancyConfig struct {
+	// Header lets cross-tenant administrators, whose credentials are not
+	// bound to a tenant, pick one.
+	Header string
+	// Default is used when a cross-tenant administrator names no tenant.
+	// Leave empty to require one.
+	Default string
+}
+
+func LoadTenancyConfig() TenancyConfig {
+	return TenancyConfig{
+		Header:  getEnv("TENANT_HEADER", "X-Tenant-ID"),
+		Default: strings.ToLower(getEnv("TENANT_DEFAULT", "default")),
+	}
+}
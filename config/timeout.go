@@ -0,0 +1,47 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+type TimeoutConfig struct {
+	// Default bounds every request; zero disables the deadline.
+	Default time.Duration
+	// Routes overrides Default per "METHOD /full/route/:param".
+	Routes map[string]time.Duration
+}
+
+// For returns the deadline that applies to route.
+func (c TimeoutConfig) For(route string) time.Duration {
+	if d, ok := c.Routes[route]; ok {
+		return d
+	}
+	return c.Default
+}
+
+// LoadTimeoutConfig reads REQUEST_TIMEOUT and REQUEST_TIMEOUT_ROUTES
+// ("POST /api/v1/clients/:slug/logo=60s,GET /api/v1/clients=5s").
+func LoadTimeoutConfig() (TimeoutConfig, error) {
+	cfg := TimeoutConfig{Routes: make(map[string]time.Duration)}
+
+	def, err := getEnvDuration("REQUEST_TIMEOUT", 10*time.Second)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.Default = def
+
+	routes, err := getEnvRoutes("REQUEST_TIMEOUT_ROUTES")
+	if err != nil {
+		return cfg, err
+	}
+	for route, value := range routes {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return cfg, fmt.Errorf("REQUEST_TIMEOUT_ROUTES: %s: invalid duration %q", route, value)
+		}
+		cfg.Routes[route] = d
+	}
+
+	return cfg, nil
+}
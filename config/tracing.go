@@ -0,0 +1,45 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Trace exporters selectable with OTEL_TRACES_EXPORTER.
+const (
+	TraceExporterNone   = "none"
+	TraceExporterOTLP   = "otlp"
+	TraceExporterStdout = "stdout"
+	TraceExporterFile   = "file"
+)
+
+// TracingConfig selects where spans go. The OTLP exporter reads its endpoint,
+// headers and protocol from the standard OTEL_EXPORTER_OTLP_* variables.
+type TracingConfig struct {
+	Exporter    string
+	File        string
+	ServiceName string
+	SampleRatio float64
+}
+
+func LoadTracingConfig() (TracingConfig, error) {
+	cfg := TracingConfig{
+		Exporter:    getEnv("OTEL_TRACES_EXPORTER", TraceExporterNone),
+		File:        getEnv("OTEL_TRACES_FILE", "traces.jsonl"),
+		ServiceName: getEnv("OTEL_SERVICE_NAME", "fullstack2024-test"),
+	}
+
+	switch cfg.Exporter {
+	case TraceExporterNone, TraceExporterOTLP, TraceExporterStdout, TraceExporterFile:
+	default:
+		return cfg, fmt.Errorf("OTEL_TRACES_EXPORTER must be none, otlp, stdout or file, got %q", cfg.Exporter)
+	}
+
+	ratio, err := strconv.ParseFloat(getEnv("OTEL_TRACES_SAMPLER_ARG", "1"), 64)
+	if err != nil || ratio < 0 || ratio > 1 {
+		return cfg, fmt.Errorf("OTEL_TRACES_SAMPLER_ARG must be a ratio between 0 and 1")
+	}
+	cfg.SampleRatio = ratio
+
+	return cfg, nil
+}
@@ -0,0 +1,188 @@
+// Package gazetteer resolves free-text city names against an embedded list of
+// the cities we operate in, so that "Jkt", "Jakarta" and "DKI Jakarta" are all
+// stored as the same place.
+package gazetteer
+
+import (
+	_ "embed"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+//go:embed cities.json
+var citiesJSON []byte
+
+type City struct {
+	Name        string   `json:"name"`
+	Province    string   `json:"province"`
+	CountryCode string   `json:"country_code"`
+	Aliases     []string `json:"aliases"`
+}
+
+type Gazetteer struct {
+	cities []City
+	index  map[string][]int
+}
+
+var (
+	defaultGazetteer *Gazetteer
+	loadOnce         sync.Once
+)
+
+// Default returns the gazetteer built from the embedded dataset.
+func Default() *Gazetteer {
+	loadOnce.Do(func() {
+		var cities []City
+		if err := json.Unmarshal(citiesJSON, &cities); err != nil {
+			panic("gazetteer: invalid embedded dataset: " + err.Error())
+		}
+		defaultGazetteer = New(cities)
+	})
+	return defaultGazetteer
+}
+
+func New(cities []City) *Gazetteer {
+	g := &Gazetteer{
+		cities: cities,
+		index:  make(map[string][]int),
+	}
+
+	for i, city := range cities {
+		for _, name := range append([]string{city.Name}, city.Aliases...) {
+			key := normalize(name)
+			if key != "" && !containsIndex(g.index[key], i) {
+				g.index[key] = append(g.index[key], i)
+			}
+		}
+	}
+
+	return g
+}
+
+// Lookup resolves a city name or alias. When countryCode is empty every
+// country is searched and the first match in dataset order wins.
+func (g *Gazetteer) Lookup(name, countryCode string) (City, bool) {
+	for _, i := range g.index[normalize(name)] {
+		if countryCode == "" || strings.EqualFold(g.cities[i].CountryCode, countryCode) {
+			return g.cities[i], true
+		}
+	}
+	return City{}, false
+}
+
+// Suggest returns up to limit canonical city names closest to name by edit
+// distance, for use in validation errors.
+func (g *Gazetteer) Suggest(name, countryCode string, limit int) []string {
+	key := normalize(name)
+	if key == "" || limit <= 0 {
+		return nil
+	}
+
+	type candidate struct {
+		city     int
+		distance int
+	}
+
+	best := make(map[int]int)
+	for alias, cities := range g.index {
+		d := levenshtein(key, alias)
+		for _, i := range cities {
+			if countryCode != "" && !strings.EqualFold(g.cities[i].CountryCode, countryCode) {
+				continue
+			}
+			if prev, ok := best[i]; !ok || d < prev {
+				best[i] = d
+			}
+		}
+	}
+
+	// Anything further away than half the input is noise rather than a typo.
+	maxDistance := len([]rune(key))/2 + 1
+
+	candidates := make([]candidate, 0, len(best))
+	for i, d := range best {
+		if d <= maxDistance {
+			candidates = append(candidates, candidate{city: i, distance: d})
+		}
+	}
+
+	sort.Slice(candidates, func(a, b int) bool {
+		if candidates[a].distance != candidates[b].distance {
+			return candidates[a].distance < candidates[b].distance
+		}
+		return g.cities[candidates[a].city].Name < g.cities[candidates[b].city].Name
+	})
+
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+
+	suggestions := make([]string, len(candidates))
+	for i, c := range candidates {
+		suggestions[i] = g.cities[c.city].Name
+	}
+
+	return suggestions
+}
+
+// normalize lowercases a name, drops punctuation and administrative prefixes
+// such as "Kota" or "Kabupaten", and collapses whitespace.
+func normalize(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			return unicode.ToLower(r)
+		case unicode.IsSpace(r) || r == '-' || r == '.' || r == ',':
+			return ' '
+		}
+		return -1
+	}, name)
+
+	fields := strings.Fields(name)
+	for len(fields) > 1 {
+		switch fields[0] {
+		case "kota", "kabupaten", "kab", "city", "of", "bandar", "kotamadya":
+			fields = fields[1:]
+			continue
+		}
+		break
+	}
+
+	return strings.Join(fields, " ")
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
+
+func containsIndex(indexes []int, i int) bool {
+	for _, v := range indexes {
+		if v == i {
+			return true
+		}
+	}
+	return false
+}
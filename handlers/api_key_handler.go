@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/farellandr/fullstack2024-test/auth"
+	"github.com/farellandr/fullstack2024-test/models"
+	"github.com/farellandr/fullstack2024-test/problem"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type APIKeyHandler struct {
+	DB *gorm.DB
+}
+
+func NewAPIKeyHandler(db *gorm.DB) *APIKeyHandler {
+	return &APIKeyHandler{
+		DB: db,
+	}
+}
+
+// db returns the database handle for a request, scoped to its tenant.
+func (h *APIKeyHandler) db(c *gin.Context) *gorm.DB {
+	return h.DB.WithContext(c.Request.Context())
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type RotateAPIKeyRequest struct {
+	// GracePeriod keeps the old key valid for this many seconds after rotation.
+	GracePeriod int `json:"grace_period_seconds" binding:"min=0,max=604800"`
+}
+
+// APIKeySecretResponse carries a newly minted key. Key is never shown again.
+type APIKeySecretResponse struct {
+	Key    string
+	APIKey models.APIKey
+}
+
+// CreateAPIKey godoc
+// @Summary Mint an API key
+// @Description Creates an API key for an integration partner. The returned Key is shown only once; only its hash is stored.
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Param key body CreateAPIKeyRequest true "Key name, scopes and optional expiry"
+// @Success 201 {object} APIKeySecretResponse "The new key and its metadata"
+// @Failure 400 {object} problem.Problem "Invalid request payload"
+// @Failure 422 {object} problem.Problem "Scope that cannot be granted to API keys, or expiry in the past"
+// @Failure 500 {object} problem.Problem "Failed to create API key"
+// @Security BearerAuth
+// @Router /admin/api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Respond(c, problem.InvalidBody(err))
+		return
+	}
+
+	for _, scope := range req.Scopes {
+		if !auth.IsAPIKeyScope(scope) {
+			problem.Respond(c, problem.Validation("Scope cannot be granted to an API key: "+scope))
+			return
+		}
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		problem.Respond(c, problem.Validation("expires_at must be in the future"))
+		return
+	}
+
+	key, secret, err := h.mint(h.db(c), models.APIKey{
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+		CreatedBy: auth.Subject(c),
+	})
+	if err != nil {
+		problem.Respond(c, problem.Internal("Failed to create API key", err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, APIKeySecretResponse{Key: secret, APIKey: key})
+}
+
+// GetAPIKeys godoc
+// @Summary List API keys
+// @Description Lists all API keys, including revoked and expired ones. Secrets are never returned.
+// @Tags api-keys
+// @Produce json
+// @Success 200 {array} models.APIKey "All API keys"
+// @Failure 500 {object} problem.Problem "Failed to retrieve API keys"
+// @Security BearerAuth
+// @Router /admin/api-keys [get]
+func (h *APIKeyHandler) GetAPIKeys(c *gin.Context) {
+	keys := []models.APIKey{}
+
+	if err := h.db(c).Order("created_at DESC").Find(&keys).Error; err != nil {
+		problem.Respond(c, problem.Internal("Failed to retrieve API keys", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke an API key
+// @Description Revokes an API key immediately. Revoking an already revoked key is a no-op.
+// @Tags api-keys
+// @Produce json
+// @Param id path int true "The API key ID"
+// @Success 200 {object} models.APIKey "The revoked key"
+// @Failure 404 {object} problem.Problem "API key not found"
+// @Failure 500 {object} problem.Problem "Failed to revoke API key"
+// @Security BearerAuth
+// @Router /admin/api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	key, ok := h.findKey(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	if key.RevokedAt == nil || key.RevokedAt.After(now) {
+		if err := h.db(c).Model(&key).Update("revoked_at", now).Error; err != nil {
+			problem.Respond(c, problem.Internal("Failed to revoke API key", err))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, key)
+}
+
+// RotateAPIKey godoc
+// @Summary Rotate an API key
+// @Description Mints a replacement key with the same name, scopes and expiry, and revokes the old key, optionally after a grace period.
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Param id path int true "The API key ID"
+// @Param rotation body RotateAPIKeyRequest false "Grace period for the old key"
+// @Success 201 {object} APIKeySecretResponse "The replacement key and its metadata"
+// @Failure 400 {object} problem.Problem "Invalid request payload"
+// @Failure 404 {object} problem.Problem "API key not found"
+// @Failure 409 {object} problem.Problem "API key is revoked or expired"
+// @Failure 500 {object} problem.Problem "Failed to rotate API key"
+// @Security BearerAuth
+// @Router /admin/api-keys/{id}/rotate [post]
+func (h *APIKeyHandler) RotateAPIKey(c *gin.Context) {
+	old, ok := h.findKey(c)
+	if !ok {
+		return
+	}
+
+	var req RotateAPIKeyRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			problem.Respond(c, problem.InvalidBody(err))
+			return
+		}
+	}
+
+	now := time.Now()
+	if !old.Active(now) {
+		problem.Respond(c, problem.Conflict("API key is revoked or expired"))
+		return
+	}
+
+	var key models.APIKey
+	var secret string
+	err := h.db(c).Transaction(func(tx *gorm.DB) error {
+		var err error
+		key, secret, err = h.mint(tx, models.APIKey{
+			Name:          old.Name,
+			Scopes:        old.Scopes,
+			ExpiresAt:     old.ExpiresAt,
+			RotatedFromID: &old.ID,
+			CreatedBy:     auth.Subject(c),
+		})
+		if err != nil {
+			return err
+		}
+
+		revokeAt := now.Add(time.Duration(req.GracePeriod) * time.Second)
+		return tx.Model(&old).Update("revoked_at", revokeAt).Error
+	})
+	if err != nil {
+		problem.Respond(c, problem.Internal("Failed to rotate API key", err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, APIKeySecretResponse{Key: secret, APIKey: key})
+}
+
+// mint generates a secret for key and stores it, returning the stored key
+// and the full secret to hand out.
+func (h *APIKeyHandler) mint(db *gorm.DB, key models.APIKey) (models.APIKey, string, error) {
+	secret, keyID, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		return key, "", err
+	}
+
+	key.KeyID = keyID
+	key.SecretHash = hash
+	if err := db.Create(&key).Error; err != nil {
+		return key, "", err
+	}
+
+	return key, secret, nil
+}
+
+func (h *APIKeyHandler) findKey(c *gin.Context) (models.APIKey, bool) {
+	var key models.APIKey
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		problem.Respond(c, problem.NotFound("API key not found"))
+		return key, false
+	}
+
+	if err := h.db(c).First(&key, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			problem.Respond(c, problem.NotFound("API key not found"))
+		} else {
+			problem.Respond(c, problem.Internal("Failed to retrieve API key", err))
+		}
+		return key, false
+	}
+
+	return key, true
+}
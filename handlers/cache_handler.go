@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/farellandr/fullstack2024-test/auth"
+	"github.com/farellandr/fullstack2024-test/cache"
+	"github.com/farellandr/fullstack2024-test/logging"
+	"github.com/farellandr/fullstack2024-test/problem"
+	"github.com/farellandr/fullstack2024-test/tenancy"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultConsistencyRate  = 100
+	defaultConsistencyLimit = 1000
+	consistencyBatchSize    = 100
+	// maxConsistencyDuration bounds how long the pacing of a check started
+	// through the API may take.
+	maxConsistencyDuration = 10 * time.Minute
+	defaultWarmUpCount     = 1000
+)
+
+type CacheHandler struct {
+	DB          *gorm.DB
+	Cache       *cache.Clients
+	Warmer      *cache.Warmer
+	Consistency *cache.ConsistencyChecker
+}
+
+func NewCacheHandler(db *gorm.DB, clientCache *cache.Clients, warmer *cache.Warmer, consistency *cache.ConsistencyChecker) *CacheHandler {
+	return &CacheHandler{
+		DB:          db,
+		Cache:       clientCache,
+		Warmer:      warmer,
+		Consistency: consistency,
+	}
+}
+
+type ConsistencyCheckRequest struct {
+	// Mode is report (the default), repair or evict.
+	Mode string `json:"mode" binding:"omitempty,oneof=report repair evict"`
+	// Rate caps the entries checked per second.
+	Rate int `json:"rate" binding:"omitempty,min=1,max=1000"`
+	// Limit caps the entries checked by this request.
+	Limit int `json:"limit" binding:"omitempty,min=1,max=10000"`
+}
+
+// CheckConsistency godoc
+// @Summary Check cached clients against the database
+// @Description Starts comparing the cached clients of the caller's tenant with their rows, soft-deleted ones included, in the background.
+// @Description In repair mode entries of live clients are rewritten and the others evicted; in evict mode every drifted entry is evicted.
+// @Description The check is rate limited, must fit Limit entries into 10 minutes at Rate, and stops after Limit entries; use cmd/check-cache for whole-cache runs.
+// @Description Poll GET /admin/cache/consistency for the report.
+// @Tags cache
+// @Accept json
+// @Produce json
+// @Param check body ConsistencyCheckRequest false "Mode, rate and limit"
+// @Success 202 {object} cache.ConsistencyStatus "Check started"
+// @Failure 400 {object} problem.Problem "Invalid request payload"
+// @Failure 409 {object} problem.Problem "A check is already running, or the cache backend cannot list its entries"
+// @Failure 422 {object} problem.Problem "Limit cannot be checked at Rate within 10 minutes"
+// @Security BearerAuth
+// @Router /admin/cache/consistency [post]
+func (h *CacheHandler) CheckConsistency(c *gin.Context) {
+	req := ConsistencyCheckRequest{
+		Mode:  cache.ModeReport,
+		Rate:  defaultConsistencyRate,
+		Limit: defaultConsistencyLimit,
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			problem.Respond(c, problem.InvalidBody(err))
+			return
+		}
+	}
+
+	if time.Duration(req.Limit)*time.Second/time.Duration(req.Rate) > maxConsistencyDuration {
+		problem.Respond(c, problem.Validation(fmt.Sprintf(
+			"Checking %d entries at %d per second would take longer than %s; raise the rate or lower the limit",
+			req.Limit, req.Rate, maxConsistencyDuration)))
+		return
+	}
+
+	// The check outlives the request but keeps its logger and trace.
+	ctx := context.WithoutCancel(c.Request.Context())
+	status, err := h.Consistency.Start(ctx, cache.ConsistencyOptions{
+		Mode:      req.Mode,
+		Tenant:    tenancy.ID(c),
+		Rate:      req.Rate,
+		Limit:     req.Limit,
+		BatchSize: min(consistencyBatchSize, req.Rate),
+	})
+	switch {
+	case errors.Is(err, cache.ErrCheckRunning):
+		problem.Respond(c, problem.Conflict("A cache consistency check is already running"))
+		return
+	case errors.Is(err, cache.ErrNotScannable):
+		problem.Respond(c, problem.Conflict("The configured cache backend cannot list its entries"))
+		return
+	case err != nil:
+		problem.Respond(c, problem.Internal("Failed to start the cache consistency check", err))
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Info("Cache consistency check requested",
+		"mode", req.Mode, "user", auth.Subject(c), "rate", req.Rate, "limit", req.Limit)
+
+	c.JSON(http.StatusAccepted, status)
+}
+
+// ConsistencyStatus godoc
+// @Summary Get cache consistency check status
+// @Description Returns the state and report, so far or final, of the running or last consistency check of the caller's tenant.
+// @Tags cache
+// @Produce json
+// @Success 200 {object} cache.ConsistencyStatus "Check status"
+// @Security BearerAuth
+// @Router /admin/cache/consistency [get]
+func (h *CacheHandler) ConsistencyStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, h.Consistency.Status(tenancy.ID(c)))
+}
+
+type WarmUpRequest struct {
+	// Strategy is recent (the default), preloading the most recently updated
+	// clients, or popular, preloading the clients this replica served most.
+	Strategy string `json:"strategy" binding:"omitempty,oneof=recent popular"`
+	// Count is the number of clients preloaded.
+	Count int `json:"count" binding:"omitempty,min=1,max=100000"`
+	// Concurrency caps the batches loaded at once.
+	Concurrency int `json:"concurrency" binding:"omitempty,min=1,max=32"`
+}
+
+// StartWarmUp godoc
+// @Summary Warm the client cache
+// @Description Preloads clients of the caller's tenant into the cache in the background, in pipelined batches.
+// @Description Only one warm-up runs at a time per tenant; poll GET /admin/cache/warm-up for progress.
+// @Tags cache
+// @Accept json
+// @Produce json
+// @Param warmup body WarmUpRequest false "Strategy, count and concurrency"
+// @Success 202 {object} cache.WarmUpProgress "Warm-up started"
+// @Failure 400 {object} problem.Problem "Invalid request payload"
+// @Failure 409 {object} problem.Problem "A warm-up of the tenant is already running"
+// @Security BearerAuth
+// @Router /admin/cache/warm-up [post]
+func (h *CacheHandler) StartWarmUp(c *gin.Context) {
+	req := WarmUpRequest{
+		Strategy:    cache.WarmRecent,
+		Count:       h.Cache.Config.WarmUpCount,
+		Concurrency: h.Cache.Config.WarmUpConcurrency,
+	}
+	if req.Count == 0 {
+		req.Count = defaultWarmUpCount
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			problem.Respond(c, problem.InvalidBody(err))
+			return
+		}
+	}
+
+	// The warm-up outlives the request but keeps its logger and trace.
+	ctx := context.WithoutCancel(c.Request.Context())
+	progress, err := h.Warmer.Start(ctx, cache.WarmUpOptions{
+		Strategy:    req.Strategy,
+		Tenant:      tenancy.ID(c),
+		Count:       req.Count,
+		Concurrency: req.Concurrency,
+		BatchSize:   h.Cache.Config.WarmUpBatchSize,
+	})
+	if errors.Is(err, cache.ErrWarmUpRunning) {
+		problem.Respond(c, problem.Conflict("A cache warm-up of this tenant is already running"))
+		return
+	}
+	if err != nil {
+		problem.Respond(c, problem.Internal("Failed to start the cache warm-up", err))
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Info("Cache warm-up requested",
+		"strategy", req.Strategy, "user", auth.Subject(c), "count", req.Count, "concurrency", req.Concurrency)
+
+	c.JSON(http.StatusAccepted, progress)
+}
+
+// WarmUpProgress godoc
+// @Summary Get cache warm-up progress
+// @Description Returns the progress of the running or last cache warm-up of the caller's tenant. The warm-up of all tenants at startup is not included.
+// @Tags cache
+// @Produce json
+// @Success 200 {object} cache.WarmUpProgress "Warm-up progress"
+// @Security BearerAuth
+// @Router /admin/cache/warm-up [get]
+func (h *CacheHandler) WarmUpProgress(c *gin.Context) {
+	c.JSON(http.StatusOK, h.Warmer.Progress(tenancy.ID(c)))
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/farellandr/fullstack2024-test/logging"
+	"github.com/farellandr/fullstack2024-test/models"
+	"github.com/farellandr/fullstack2024-test/tenancy"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// clientLoadTimeout bounds a shared client load, which is not tied to the
+// request that started it.
+const clientLoadTimeout = 5 * time.Second
+
+// loadClient fetches a client from the database and caches it.
+// Concurrent misses for the same slug share a single query; a waiting
+// request gives up when its own context ends, without failing the others.
+func (h *ClientHandler) loadClient(c *gin.Context, slug string) (*models.Client, error) {
+	ctx := c.Request.Context()
+	tenant := tenancy.ID(c)
+
+	ch := h.loads.DoChan(tenant+"/"+slug, func() (interface{}, error) {
+		return h.fetchClient(context.WithoutCancel(ctx), tenant, slug)
+	})
+
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(*models.Client), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// refreshClient reloads a stale client in the background. The caller has
+// already been served the stale copy.
+func (h *ClientHandler) refreshClient(c *gin.Context, slug string) {
+	ctx := context.WithoutCancel(c.Request.Context())
+	tenant := tenancy.ID(c)
+
+	h.loads.DoChan(tenant+"/"+slug, func() (interface{}, error) {
+		return h.fetchClient(ctx, tenant, slug)
+	})
+}
+
+// fetchClient reads a client and writes it, or the fact that it does not
+// exist, to the cache.
+func (h *ClientHandler) fetchClient(ctx context.Context, tenant, slug string) (*models.Client, error) {
+	ctx, cancel := context.WithTimeout(ctx, clientLoadTimeout)
+	defer cancel()
+
+	var client models.Client
+	err := h.DB.WithContext(ctx).Preload("Tags").Where("slug = ?", slug).First(&client).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if err := h.Cache.SetMissing(ctx, tenant, slug); err != nil {
+			logging.FromContext(ctx).Warn("Failed to cache unknown client slug", "error", err)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.Cache.Fill(ctx, tenant, slug, &client); err != nil {
+		logging.FromContext(ctx).Warn("Failed to cache client", "error", err)
+	}
+
+	return &client, nil
+}
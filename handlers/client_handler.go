@@ -1,47 +1,74 @@
 package handlers
 
 import (
-	"log"
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
 
+	"github.com/farellandr/fullstack2024-test/auth"
+	"github.com/farellandr/fullstack2024-test/cache"
+	"github.com/farellandr/fullstack2024-test/logging"
+	"github.com/farellandr/fullstack2024-test/metrics"
 	"github.com/farellandr/fullstack2024-test/models"
+	"github.com/farellandr/fullstack2024-test/problem"
+	"github.com/farellandr/fullstack2024-test/tenancy"
 	"github.com/farellandr/fullstack2024-test/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"golang.org/x/sync/singleflight"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ClientHandler struct {
-	DB          *gorm.DB
-	RedisClient *utils.RedisClient
-	S3Service   *utils.S3Service
+	DB        *gorm.DB
+	Cache     *cache.Clients
+	S3Service *utils.S3Service
+
+	// loads coalesces concurrent cache misses for the same client.
+	loads singleflight.Group
 }
 
-func NewClientHandler(db *gorm.DB, redisClient *utils.RedisClient, s3Service *utils.S3Service) *ClientHandler {
+func NewClientHandler(db *gorm.DB, clientCache *cache.Clients, s3Service *utils.S3Service) *ClientHandler {
 	return &ClientHandler{
-		DB:          db,
-		RedisClient: redisClient,
-		S3Service:   s3Service,
+		DB:        db,
+		Cache:     clientCache,
+		S3Service: s3Service,
 	}
 }
 
+// db returns the database handle bound to the request context, and so to
+// its deadline and tenant. The tenant filter comes from the gorm callbacks
+// registered by tenancy.Register, which raw SQL bypasses: queries built with
+// Raw or Exec must filter tenant_id themselves.
+func (h *ClientHandler) db(c *gin.Context) *gorm.DB {
+	return h.DB.WithContext(c.Request.Context())
+}
+
 // CreateClient godoc
 // @Summary Create a new client
 // @Description Creates a new client record in the database and caches it in Redis.
+// @Description Phone numbers are stored in E.164 form; structured address components are also rendered into Address unless only a free-text Address and no Street is sent.
+// @Description City is canonicalised against the built-in gazetteer and sets Province and CountryCode; a Province sent with it must match.
+// @Description When ClientPrefix is omitted a free one is derived from Name.
 // @Tags clients
 // @Accept json
 // @Produce json
 // @Param client body models.Client true "Client object to be created"
 // @Success 201 {object} models.Client "Successfully created client"
-// @Failure 400 {object} map[string]string "Invalid request payload"
-// @Failure 500 {object} map[string]string "Failed to create client"
+// @Failure 400 {object} problem.Problem "Invalid request payload"
+// @Failure 409 {object} problem.Problem "Client prefix already in use"
+// @Failure 422 {object} problem.Problem "Invalid address, unknown city (with suggestions), phone number, prefix or parent client, or hierarchy too deep"
+// @Failure 500 {object} problem.Problem "Failed to create client"
+// @Security BearerAuth
+// @Security APIKeyAuth
 // @Router /clients [post]
 func (h *ClientHandler) CreateClient(c *gin.Context) {
 	var client models.Client
 
 	if err := c.ShouldBindJSON(&client); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		problem.Respond(c, problem.InvalidBody(err))
 		return
 	}
 
@@ -49,15 +76,70 @@ func (h *ClientHandler) CreateClient(c *gin.Context) {
 		client.Slug = generateSlug(client.Name)
 	}
 
-	if err := h.DB.Create(&client).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create client: " + err.Error()})
+	// Tags are managed through the /clients/:slug/tags endpoints.
+	client.Tags = []models.Tag{}
+
+	if err := normalizeContactDetails(&client, nil); err != nil {
+		respondContactError(c, err)
 		return
 	}
 
-	if h.RedisClient != nil {
-		if err := h.RedisClient.SetClientData(client.Slug, client); err != nil {
-			log.Printf("Warning: Failed to save client to Redis: %v", err)
+	autoPrefix := strings.TrimSpace(client.ClientPrefix) == ""
+	if !autoPrefix {
+		prefix, err := normalizeClientPrefix(client.ClientPrefix)
+		if err != nil {
+			problem.Respond(c, problem.Validation(err.Error()))
+			return
 		}
+		client.ClientPrefix = prefix
+	}
+
+	// A generated prefix can be taken by a concurrent create between
+	// allocation and insert; the unique index catches that and we retry.
+	for attempt := 1; ; attempt++ {
+		if autoPrefix {
+			prefix, err := allocatePrefix(h.db(c), client.Name)
+			if errors.Is(err, errNoFreePrefix) {
+				problem.Respond(c, problem.Validation("Cannot derive a client prefix from the name; provide ClientPrefix"))
+				return
+			}
+			if err != nil {
+				problem.Respond(c, problem.Internal("Failed to allocate client prefix", err))
+				return
+			}
+			client.ClientPrefix = prefix
+		}
+
+		// The parent is validated in the transaction that inserts the client,
+		// holding locks that keep a concurrent delete from orphaning it.
+		err := h.db(c).Transaction(func(tx *gorm.DB) error {
+			if client.ParentID != nil {
+				if err := validateParent(tx, 0, *client.ParentID); err != nil {
+					return err
+				}
+			}
+			return tx.Omit(clause.Associations).Create(&client).Error
+		})
+		if err == nil {
+			break
+		}
+		if isParentError(err) {
+			respondParentError(c, err)
+			return
+		}
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			if autoPrefix && attempt < maxPrefixAttempts {
+				continue
+			}
+			problem.Respond(c, problem.Conflict("Client prefix already in use"))
+			return
+		}
+		problem.Respond(c, problem.Internal("Failed to create client", err))
+		return
+	}
+
+	if err := h.Cache.Set(c.Request.Context(), tenancy.ID(c), client.Slug, &client); err != nil {
+		logging.FromContext(c.Request.Context()).Warn("Failed to cache client", "error", err)
 	}
 
 	c.JSON(http.StatusCreated, client)
@@ -65,105 +147,228 @@ func (h *ClientHandler) CreateClient(c *gin.Context) {
 
 // GetAllClients godoc
 // @Summary Get all clients
-// @Description Retrieves a list of all client records from the database.
+// @Description Retrieves client records, optionally filtered by tags or a search term, from the cache or the database.
+// @Description Without page or page_size the whole list is returned as an array; with either, one page is returned as a ClientPage object.
 // @Tags clients
 // @Produce json
-// @Success 200 {array} models.Client "A list of all clients"
-// @Failure 500 {object} map[string]string "Failed to retrieve clients"
+// @Param q query string false "Case-insensitive search in name, slug, prefix and city"
+// @Param tag query string false "Only clients carrying this tag"
+// @Param tags_any query string false "Comma-separated tags; only clients carrying at least one of them"
+// @Param tags_all query string false "Comma-separated tags; only clients carrying all of them"
+// @Param page query int false "Page number, starting at 1"
+// @Param page_size query int false "Clients per page (1-100, default 20)"
+// @Success 200 {array} models.Client "A list of all clients, or a ClientPage when paginated"
+// @Failure 400 {object} problem.Problem "Invalid filter or pagination"
+// @Failure 500 {object} problem.Problem "Failed to retrieve clients"
+// @Security BearerAuth
+// @Security APIKeyAuth
 // @Router /clients [get]
 func (h *ClientHandler) GetAllClients(c *gin.Context) {
-	var clients []models.Client
+	q, err := parseClientListQuery(c.Request.URL.Query())
+	if err != nil {
+		problem.Respond(c, problem.BadRequest(err.Error()))
+		return
+	}
+
+	ctx := c.Request.Context()
+	tenant := tenancy.ID(c)
+	key := q.cacheKey()
+
+	// The generation is read before the database so that a page computed
+	// while a client changes is filed under the generation it replaces.
+	var gen string
+	cacheable := false
+	if h.Cache.Config.ListTTL > 0 {
+		gen, err = h.Cache.ListGeneration(ctx, tenant)
+		if err == nil {
+			body := q.newBody()
+			if err = h.Cache.GetList(ctx, tenant, gen, key, body); err == nil {
+				metrics.CacheResult("client_list", metrics.CacheHit)
+				c.JSON(http.StatusOK, body)
+				return
+			}
+		}
+
+		if errors.Is(err, cache.ErrMiss) {
+			metrics.CacheResult("client_list", metrics.CacheMiss)
+			cacheable = true
+		} else {
+			metrics.CacheResult("client_list", metrics.CacheError)
+			logging.FromContext(ctx).Warn("Failed to read cached client list", "error", err)
+		}
+	}
 
-	if err := h.DB.Find(&clients).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve clients"})
+	body, err := h.listClients(c, q)
+	if err != nil {
+		problem.Respond(c, problem.Internal("Failed to retrieve clients", err))
 		return
 	}
 
-	c.JSON(http.StatusOK, clients)
+	if cacheable {
+		if err := h.Cache.SetList(ctx, tenant, gen, key, body); err != nil {
+			logging.FromContext(ctx).Warn("Failed to cache client list", "error", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, body)
+}
+
+// listClients runs a client list query against the database and returns the
+// response body: the clients, or a ClientPage when paginated.
+func (h *ClientHandler) listClients(c *gin.Context, q clientListQuery) (interface{}, error) {
+	clients := []models.Client{}
+	query := q.apply(h.db(c), h.db(c).Model(&models.Client{})).Session(&gorm.Session{})
+
+	if !q.Paginated {
+		if err := query.Preload("Tags").Order("id").Find(&clients).Error; err != nil {
+			return nil, err
+		}
+		return clients, nil
+	}
+
+	page := ClientPage{Page: q.Page, PageSize: q.PageSize}
+	if err := query.Count(&page.Total).Error; err != nil {
+		return nil, err
+	}
+
+	err := query.Preload("Tags").Order("id").
+		Limit(q.PageSize).Offset((q.Page - 1) * q.PageSize).
+		Find(&clients).Error
+	if err != nil {
+		return nil, err
+	}
+	page.Items = clients
+
+	return page, nil
 }
 
 // GetClientBySlug godoc
 // @Summary Get client by slug
-// @Description Retrieves a single client record by its unique slug, first checking the Redis cache and then the database.
+// @Description Retrieves a single client record by its unique slug, first checking the cache and then the database.
 // @Tags clients
 // @Produce json
 // @Param slug path string true "The unique slug of the client to retrieve"
 // @Success 200 {object} models.Client "The requested client"
-// @Failure 404 {object} map[string]string "Client not found"
+// @Failure 404 {object} problem.Problem "Client not found"
+// @Security BearerAuth
+// @Security APIKeyAuth
 // @Router /clients/{slug} [get]
 func (h *ClientHandler) GetClientBySlug(c *gin.Context) {
 	slug := c.Param("slug")
-	var client models.Client
-
-	if h.RedisClient != nil {
-		data, err := h.RedisClient.GetClientData(slug)
-		if err == nil {
-			c.Data(http.StatusOK, "application/json", []byte(data))
-			return
-		}
-	}
-
-	if err := h.DB.Where("slug = ?", slug).First(&client).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Client not found"})
+	tenant := tenancy.ID(c)
+
+	client, stale, err := h.Cache.Get(c.Request.Context(), tenant, slug)
+	switch {
+	case err == nil && stale:
+		metrics.CacheResult("client", metrics.CacheStale)
+		h.Cache.Popularity.Hit(tenant, slug)
+		h.refreshClient(c, slug)
+		c.JSON(http.StatusOK, client)
+		return
+	case err == nil:
+		metrics.CacheResult("client", metrics.CacheHit)
+		h.Cache.Popularity.Hit(tenant, slug)
+		c.JSON(http.StatusOK, client)
 		return
+	case errors.Is(err, cache.ErrNotFound):
+		metrics.CacheResult("client", metrics.CacheNegativeHit)
+		respondClientLookupError(c, gorm.ErrRecordNotFound)
+		return
+	case errors.Is(err, cache.ErrMiss):
+		metrics.CacheResult("client", metrics.CacheMiss)
+	default:
+		metrics.CacheResult("client", metrics.CacheError)
 	}
 
-	if h.RedisClient != nil {
-		if err := h.RedisClient.SetClientData(client.Slug, client); err != nil {
-			log.Printf("Warning: Failed to save client to Redis: %v", err)
-		}
+	client, err = h.loadClient(c, slug)
+	if err != nil {
+		respondClientLookupError(c, err)
+		return
 	}
+	h.Cache.Popularity.Hit(tenant, slug)
 
 	c.JSON(http.StatusOK, client)
 }
 
 // UpdateClient godoc
 // @Summary Update a client
-// @Description Updates an existing client's data identified by its slug and refreshes the Redis cache.
+// @Description Updates an existing client's data identified by its slug and refreshes the cache.
+// @Description Address is rebuilt from the structured components, except that a free-text Address without components is kept until Street is sent.
+// @Description A new City replaces Province and CountryCode with those of the city; a Province sent with it must match.
 // @Tags clients
 // @Accept json
 // @Produce json
 // @Param slug path string true "The unique slug of the client to update"
 // @Param client body models.Client true "Updated client object"
 // @Success 200 {object} models.Client "Successfully updated client"
-// @Failure 400 {object} map[string]string "Invalid request payload"
-// @Failure 404 {object} map[string]string "Client not found"
-// @Failure 500 {object} map[string]string "Failed to update client"
+// @Failure 400 {object} problem.Problem "Invalid request payload"
+// @Failure 404 {object} problem.Problem "Client not found"
+// @Failure 409 {object} problem.Problem "Client prefix already in use"
+// @Failure 422 {object} problem.Problem "Invalid address, unknown city (with suggestions), phone number, prefix or parent client, hierarchy cycle or hierarchy too deep"
+// @Failure 500 {object} problem.Problem "Failed to update client"
+// @Security BearerAuth
+// @Security APIKeyAuth
 // @Router /clients/{slug} [put]
 func (h *ClientHandler) UpdateClient(c *gin.Context) {
 	slug := c.Param("slug")
 	var client models.Client
 
-	if err := h.DB.Where("slug = ?", slug).First(&client).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Client not found"})
+	if err := h.db(c).Preload("Tags").Where("slug = ?", slug).First(&client).Error; err != nil {
+		respondClientLookupError(c, err)
 		return
 	}
 
 	var updatedClient models.Client
 	if err := c.ShouldBindJSON(&updatedClient); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		problem.Respond(c, problem.InvalidBody(err))
 		return
 	}
+	updatedClient.Tags = nil
+	updatedClient.TenantID = ""
 
-	if err := h.DB.Model(&client).Updates(updatedClient).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update client"})
+	if err := normalizeContactDetails(&updatedClient, &client); err != nil {
+		respondContactError(c, err)
 		return
 	}
 
-	if h.RedisClient != nil {
-		if err := h.RedisClient.DeleteClientData(slug); err != nil {
-			log.Printf("Warning: Failed to delete client from Redis: %v", err)
+	if updatedClient.ClientPrefix != "" {
+		prefix, err := normalizeClientPrefix(updatedClient.ClientPrefix)
+		if err != nil {
+			problem.Respond(c, problem.Validation(err.Error()))
+			return
 		}
+		updatedClient.ClientPrefix = prefix
+	}
 
-		if client.Slug != slug {
-			if err := h.RedisClient.SetClientData(client.Slug, client); err != nil {
-				log.Printf("Warning: Failed to save client to Redis: %v", err)
-			}
-		} else {
-			if err := h.RedisClient.SetClientData(slug, client); err != nil {
-				log.Printf("Warning: Failed to save client to Redis: %v", err)
+	// The parent is validated in the transaction that sets it, holding locks
+	// that keep concurrent reparents from creating a cycle between them.
+	err := h.db(c).Transaction(func(tx *gorm.DB) error {
+		if updatedClient.ParentID != nil {
+			if err := validateParent(tx, client.ID, *updatedClient.ParentID); err != nil {
+				return err
 			}
 		}
+		return tx.Model(&client).Omit(clause.Associations).Updates(updatedClient).Error
+	})
+	switch {
+	case err == nil:
+	case isParentError(err):
+		respondParentError(c, err)
+		return
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		problem.Respond(c, problem.Conflict("Client prefix already in use"))
+		return
+	default:
+		problem.Respond(c, problem.Internal("Failed to update client", err))
+		return
+	}
+
+	if err := h.Cache.Delete(c.Request.Context(), tenancy.ID(c), slug); err != nil {
+		logging.FromContext(c.Request.Context()).Warn("Failed to evict client from the cache", "error", err)
+	}
+
+	if err := h.Cache.Set(c.Request.Context(), tenancy.ID(c), client.Slug, &client); err != nil {
+		logging.FromContext(c.Request.Context()).Warn("Failed to cache client", "error", err)
 	}
 
 	c.JSON(http.StatusOK, client)
@@ -171,31 +376,76 @@ func (h *ClientHandler) UpdateClient(c *gin.Context) {
 
 // DeleteClient godoc
 // @Summary Delete a client
-// @Description Deletes a client record from the database and removes it from the Redis cache by its unique slug.
+// @Description Deletes a client record from the database and removes it from the cache by its unique slug.
+// @Description Deleting a client with subsidiaries is refused unless cascade=true, which deletes the whole subtree.
 // @Tags clients
 // @Produce json
 // @Param slug path string true "The unique slug of the client to delete"
+// @Param cascade query bool false "Also delete all descendant clients"
 // @Success 200 {object} map[string]string "Successfully deleted client"
-// @Failure 404 {object} map[string]string "Client not found"
-// @Failure 500 {object} map[string]string "Failed to delete client"
+// @Failure 400 {object} problem.Problem "Invalid cascade option"
+// @Failure 404 {object} problem.Problem "Client not found"
+// @Failure 409 {object} problem.Problem "Client has child clients, or a hierarchy deeper than the supported limit"
+// @Failure 500 {object} problem.Problem "Failed to delete client"
+// @Security BearerAuth
+// @Security APIKeyAuth
 // @Router /clients/{slug} [delete]
 func (h *ClientHandler) DeleteClient(c *gin.Context) {
 	slug := c.Param("slug")
 	var client models.Client
 
-	if err := h.DB.Where("slug = ?", slug).First(&client).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Client not found"})
+	if err := h.db(c).Where("slug = ?", slug).First(&client).Error; err != nil {
+		respondClientLookupError(c, err)
 		return
 	}
 
-	if err := h.DB.Delete(&client).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete client"})
+	cascade, err := strconv.ParseBool(c.DefaultQuery("cascade", "false"))
+	if err != nil {
+		problem.Respond(c, problem.BadRequest("cascade must be true or false"))
+		return
+	}
+
+	// The subtree is locked before it is checked and deleted: creates and
+	// reparents under any of its clients lock that client's ancestors, so
+	// they wait for the delete and then find their parent gone.
+	var slugs []string
+	err = h.db(c).Transaction(func(tx *gorm.DB) error {
+		ids, err := lockHierarchy(tx, []uint{client.ID}, func() ([]uint, error) {
+			return descendantIDs(tx, client.ID)
+		})
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if len(ids) > 1 && !cascade {
+			return errHasChildren
+		}
+
+		if err := tx.Model(&models.Client{}).Where("id IN ?", ids).Pluck("slug", &slugs).Error; err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", ids).Delete(&models.Client{}).Error
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		respondClientLookupError(c, err)
+		return
+	case errors.Is(err, errHasChildren):
+		problem.Respond(c, problem.Conflict("Client has child clients; pass cascade=true to delete them as well"))
+		return
+	default:
+		respondHierarchyError(c, "Failed to delete client", err)
 		return
 	}
 
-	if h.RedisClient != nil {
-		if err := h.RedisClient.DeleteClientData(slug); err != nil {
-			log.Printf("Warning: Failed to delete client from Redis: %v", err)
+	logging.FromContext(c.Request.Context()).Info("Client deleted", "slug", slug, "user", auth.Subject(c), "removed", len(slugs))
+
+	for _, s := range slugs {
+		if err := h.Cache.Delete(c.Request.Context(), tenancy.ID(c), s); err != nil {
+			logging.FromContext(c.Request.Context()).Warn("Failed to evict client from the cache", "error", err)
 		}
 	}
 
@@ -219,57 +469,51 @@ func generateSlug(name string) string {
 
 // UploadClientLogo godoc
 // @Summary Upload client logo
-// @Description Uploads a client logo image to S3, updates the client record in the database with the S3 URL, and refreshes the Redis cache.
+// @Description Uploads a client logo image to S3, updates the client record in the database with the S3 URL, and refreshes the cache.
 // @Tags clients
 // @Accept multipart/form-data
 // @Produce json
 // @Param slug path string true "The unique slug of the client to update the logo for"
 // @Param logo formData file true "The logo file to upload (e.g., .png, .jpg)"
 // @Success 200 {object} map[string]string "Successfully uploaded logo"
-// @Failure 400 {object} map[string]string "Invalid file upload"
-// @Failure 404 {object} map[string]string "Client not found"
-// @Failure 500 {object} map[string]string "Failed to upload logo or update client"
+// @Failure 400 {object} problem.Problem "Invalid file upload"
+// @Failure 404 {object} problem.Problem "Client not found"
+// @Failure 500 {object} problem.Problem "Failed to upload logo or update client"
+// @Security BearerAuth
+// @Security APIKeyAuth
 // @Router /clients/{slug}/upload-logo [post]
 func (h *ClientHandler) UploadClientLogo(c *gin.Context) {
 	slug := c.Param("slug")
 	var client models.Client
 
-	if err := h.DB.Where("slug = ?", slug).First(&client).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Client not found"})
+	if err := h.db(c).Where("slug = ?", slug).First(&client).Error; err != nil {
+		respondClientLookupError(c, err)
 		return
 	}
 
 	file, err := c.FormFile("logo")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No file provided"})
+		problem.Respond(c, problem.BadRequest("No file provided"))
 		return
 	}
 
 	if h.S3Service == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "S3 service not available"})
+		problem.Respond(c, problem.UpstreamUnavailable("S3 service not available"))
 		return
 	}
 
-	logoURL, err := h.S3Service.UploadFile(file)
+	logoURL, err := h.S3Service.UploadFile(c.Request.Context(), tenancy.ID(c), file)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload file: " + err.Error()})
+		problem.Respond(c, problem.Internal("Failed to upload file", err))
 		return
 	}
 
-	if err := h.DB.Model(&client).Update("client_logo", logoURL).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update client logo"})
+	if err := h.db(c).Model(&client).Update("client_logo", logoURL).Error; err != nil {
+		problem.Respond(c, problem.Internal("Failed to update client logo", err))
 		return
 	}
 
-	if h.RedisClient != nil {
-		if err := h.DB.Where("slug = ?", slug).First(&client).Error; err != nil {
-			log.Printf("Warning: Failed to get updated client for Redis: %v", err)
-		} else {
-			if err := h.RedisClient.SetClientData(slug, client); err != nil {
-				log.Printf("Warning: Failed to update client in Redis: %v", err)
-			}
-		}
-	}
+	h.refreshClientCache(c, &client)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":  "Logo uploaded successfully",
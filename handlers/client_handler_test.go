@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/farellandr/fullstack2024-test/auth"
+	"github.com/farellandr/fullstack2024-test/cache"
+	"github.com/farellandr/fullstack2024-test/config"
+	"github.com/farellandr/fullstack2024-test/models"
+	"github.com/farellandr/fullstack2024-test/tenancy"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+const testJWTSecret = "test-secret"
+
+// Subjects of test tokens: testEditor holds the manager role in the alpha
+// and beta tenants, testRoaming in every tenant, and testAdmin the admin
+// role in every tenant.
+const (
+	testEditor  = "editor@example.com"
+	testRoaming = "roaming@example.com"
+	testAdmin   = "admin@example.com"
+)
+
+func newTestRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{TranslateError: true})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err := models.Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if err := tenancy.Register(db); err != nil {
+		t.Fatalf("register tenancy: %v", err)
+	}
+
+	roles := auth.NewRoleStore(db)
+	if err := roles.Seed([]string{testAdmin}); err != nil {
+		t.Fatalf("seed roles: %v", err)
+	}
+	var manager models.Role
+	if err := db.Where("name = ?", "manager").First(&manager).Error; err != nil {
+		t.Fatalf("load manager role: %v", err)
+	}
+	for _, assignment := range []models.RoleAssignment{
+		{TenantID: "alpha", Subject: testEditor, RoleID: manager.ID},
+		{TenantID: "beta", Subject: testEditor, RoleID: manager.ID},
+		{TenantID: models.AllTenants, Subject: testRoaming, RoleID: manager.ID},
+	} {
+		if err := db.WithContext(tenancy.WithoutTenant(context.Background())).Create(&assignment).Error; err != nil {
+			t.Fatalf("assign manager role: %v", err)
+		}
+	}
+
+	authConfig := config.AuthConfig{Algorithm: "HS256", Secret: testJWTSecret, TenantClaim: "tenant_id"}
+	verifier, err := auth.NewJWTVerifier(authConfig)
+	if err != nil {
+		t.Fatalf("create JWT verifier: %v", err)
+	}
+
+	cacheConfig := config.CacheConfig{TTL: time.Minute, NegativeTTL: time.Minute, ListTTL: time.Minute}
+	h := NewClientHandler(db, cache.NewClients(cache.NewMemory(100), cacheConfig), nil)
+
+	read := auth.Require(auth.PermClientsRead)
+	write := auth.Require(auth.PermClientsWrite)
+	remove := auth.Require(auth.PermClientsDelete)
+
+	router := gin.New()
+	api := router.Group("/api/v1",
+		auth.Middleware(verifier, auth.NewAPIKeyStore(db), roles),
+		tenancy.Middleware(config.TenancyConfig{Header: "X-Tenant-ID", Default: "default"}, auth.TenantID, auth.CrossTenant),
+	)
+	api.POST("/clients", write, h.CreateClient)
+	api.GET("/clients", read, h.GetAllClients)
+	api.GET("/clients/:slug", read, h.GetClientBySlug)
+	api.PUT("/clients/:slug", write, h.UpdateClient)
+	api.DELETE("/clients/:slug", remove, h.DeleteClient)
+	api.GET("/clients/:slug/ancestors", read, h.GetClientAncestors)
+	api.GET("/clients/:slug/tree", read, h.GetClientTree)
+
+	return router, db
+}
+
+// testToken signs a token for subject, bound to tenant unless it is empty.
+func testToken(subject, tenant string) string {
+	claims := jwt.MapClaims{"sub": subject, "iat": time.Now().Unix(), "exp": time.Now().Add(time.Hour).Unix()}
+	if tenant != "" {
+		claims["tenant_id"] = tenant
+	}
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testJWTSecret))
+	return token
+}
+
+// doRequest calls the API as testEditor with a token bound to tenant.
+func doRequest(router *gin.Engine, method, path, tenant string, body interface{}) *httptest.ResponseRecorder {
+	return doRequestAs(router, method, path, testToken(testEditor, tenant), "", body)
+}
+
+// doRequestAs calls the API with token, naming tenantHeader in the tenant
+// header when it is set.
+func doRequestAs(router *gin.Engine, method, path, token, tenantHeader string, body interface{}) *httptest.ResponseRecorder {
+	var payload bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&payload).Encode(body)
+	}
+
+	req := httptest.NewRequest(method, path, &payload)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	if tenantHeader != "" {
+		req.Header.Set("X-Tenant-ID", tenantHeader)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestClientsAreIsolatedBetweenTenants(t *testing.T) {
+	router, db := newTestRouter(t)
+
+	w := doRequest(router, http.MethodPost, "/api/v1/clients", "alpha", gin.H{"Name": "Acme Holdings", "City": "Jkt"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: got %d, body %s", w.Code, w.Body)
+	}
+
+	var created models.Client
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode created client: %v", err)
+	}
+	if created.TenantID != "alpha" {
+		t.Fatalf("created client tenant = %q, want alpha", created.TenantID)
+	}
+	path := "/api/v1/clients/" + created.Slug
+
+	if w := doRequest(router, http.MethodGet, path, "alpha", nil); w.Code != http.StatusOK {
+		t.Fatalf("read from own tenant: got %d, body %s", w.Code, w.Body)
+	}
+
+	for _, tc := range []struct {
+		method string
+		body   interface{}
+	}{
+		{http.MethodGet, nil},
+		{http.MethodPut, gin.H{"Name": "Hijacked"}},
+		{http.MethodDelete, nil},
+	} {
+		if w := doRequest(router, tc.method, path, "beta", tc.body); w.Code != http.StatusNotFound {
+			t.Errorf("%s from other tenant: got %d, want 404", tc.method, w.Code)
+		}
+	}
+
+	w = doRequest(router, http.MethodGet, "/api/v1/clients", "beta", nil)
+	if w.Code != http.StatusOK || w.Body.String() != "[]" {
+		t.Errorf("list from other tenant: got %d %s, want 200 []", w.Code, w.Body)
+	}
+
+	var stored models.Client
+	if err := db.WithContext(tenancy.WithTenant(context.Background(), "alpha")).First(&stored, created.ID).Error; err != nil {
+		t.Fatalf("reload client: %v", err)
+	}
+	if stored.Name != "Acme Holdings" {
+		t.Errorf("client name = %q after cross-tenant update, want unchanged", stored.Name)
+	}
+
+	if err := db.First(&models.Client{}).Error; !errors.Is(err, tenancy.ErrMissingTenant) {
+		t.Errorf("query without tenant: got %v, want ErrMissingTenant", err)
+	}
+}
+
+func TestUnboundTokensCannotChooseATenant(t *testing.T) {
+	router, db := newTestRouter(t)
+
+	w := doRequest(router, http.MethodPost, "/api/v1/clients", "alpha", gin.H{"Name": "Acme Holdings", "City": "Jkt"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: got %d, body %s", w.Code, w.Body)
+	}
+	var created models.Client
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode created client: %v", err)
+	}
+	path := "/api/v1/clients/" + created.Slug
+
+	// testRoaming holds client permissions in every tenant but not
+	// tenants:all, so only the tenant binding stops its unbound token.
+	unbound := testToken(testRoaming, "")
+	for _, tc := range []struct {
+		name   string
+		token  string
+		header string
+		method string
+		path   string
+		body   interface{}
+	}{
+		{"unbound read", unbound, "alpha", http.MethodGet, path, nil},
+		{"unbound list", unbound, "alpha", http.MethodGet, "/api/v1/clients", nil},
+		{"unbound update", unbound, "alpha", http.MethodPut, path, gin.H{"Name": "Hijacked"}},
+		{"unbound delete", unbound, "alpha", http.MethodDelete, path, nil},
+		{"unbound create in default tenant", unbound, "", http.MethodPost, "/api/v1/clients", gin.H{"Name": "Default Co", "City": "Jkt"}},
+		{"unbound token without role assignments", testToken(testEditor, ""), "alpha", http.MethodGet, path, nil},
+		{"bound token naming another tenant", testToken(testEditor, "beta"), "alpha", http.MethodPut, path, gin.H{"Name": "Hijacked"}},
+	} {
+		if w := doRequestAs(router, tc.method, tc.path, tc.token, tc.header, tc.body); w.Code != http.StatusForbidden {
+			t.Errorf("%s: got %d, want 403, body %s", tc.name, w.Code, w.Body)
+		}
+	}
+
+	var stored models.Client
+	if err := db.WithContext(tenancy.WithTenant(context.Background(), "alpha")).First(&stored, created.ID).Error; err != nil {
+		t.Fatalf("reload client: %v", err)
+	}
+	if stored.Name != "Acme Holdings" {
+		t.Errorf("client name = %q after unbound update, want unchanged", stored.Name)
+	}
+
+	if w := doRequestAs(router, http.MethodGet, path, testToken(testRoaming, "alpha"), "", nil); w.Code != http.StatusOK {
+		t.Errorf("bound token of subject with roles in every tenant: got %d, body %s", w.Code, w.Body)
+	}
+
+	// Cross-tenant administrators pick the tenant with the header.
+	admin := testToken(testAdmin, "")
+	if w := doRequestAs(router, http.MethodGet, path, admin, "alpha", nil); w.Code != http.StatusOK {
+		t.Errorf("cross-tenant admin read: got %d, body %s", w.Code, w.Body)
+	}
+	if w := doRequestAs(router, http.MethodGet, path, admin, "beta", nil); w.Code != http.StatusNotFound {
+		t.Errorf("cross-tenant admin read in other tenant: got %d, want 404", w.Code)
+	}
+
+	// A bound token of a subject holding the admin role in every tenant
+	// still acts only in its own tenant.
+	if w := doRequestAs(router, http.MethodGet, path, testToken(testAdmin, "beta"), "alpha", nil); w.Code != http.StatusForbidden {
+		t.Errorf("bound admin naming another tenant: got %d, want 403", w.Code)
+	}
+}
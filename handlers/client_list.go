@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/farellandr/fullstack2024-test/models"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+	maxSearchLength = 100
+)
+
+// ClientPage is one page of a paginated client list.
+type ClientPage struct {
+	Items    []models.Client
+	Page     int
+	PageSize int
+	Total    int64
+}
+
+// clientListQuery is a parsed and normalised GetAllClients query.
+type clientListQuery struct {
+	Search  string
+	Tag     []string
+	TagsAny []string
+	TagsAll []string
+
+	// Paginated is set when page or page_size was given; without it the
+	// whole list is returned as a plain array.
+	Paginated bool
+	Page      int
+	PageSize  int
+}
+
+func parseClientListQuery(values url.Values) (clientListQuery, error) {
+	var q clientListQuery
+	var err error
+
+	q.Search = strings.ToLower(strings.Join(strings.Fields(values.Get("q")), " "))
+	if len(q.Search) > maxSearchLength {
+		return q, fmt.Errorf("q must be at most %d characters", maxSearchLength)
+	}
+
+	if q.Tag, err = splitTagParam(values.Get("tag")); err != nil {
+		return q, err
+	}
+	if len(q.Tag) > 1 {
+		return q, errors.New("tag accepts a single tag; use tags_any or tags_all for several")
+	}
+	if q.TagsAny, err = splitTagParam(values.Get("tags_any")); err != nil {
+		return q, err
+	}
+	if q.TagsAll, err = splitTagParam(values.Get("tags_all")); err != nil {
+		return q, err
+	}
+
+	q.Page, q.PageSize = 1, defaultPageSize
+	if v := values.Get("page"); v != "" {
+		q.Paginated = true
+		if q.Page, err = strconv.Atoi(v); err != nil || q.Page < 1 {
+			return q, errors.New("page must be a positive integer")
+		}
+	}
+	if v := values.Get("page_size"); v != "" {
+		q.Paginated = true
+		if q.PageSize, err = strconv.Atoi(v); err != nil || q.PageSize < 1 || q.PageSize > maxPageSize {
+			return q, fmt.Errorf("page_size must be between 1 and %d", maxPageSize)
+		}
+	}
+
+	return q, nil
+}
+
+// cacheKey renders the query canonically, so that equivalent requests
+// (reordered parameters or tags, different case or spacing) share a cache
+// entry.
+func (q clientListQuery) cacheKey() string {
+	key := url.Values{}
+	key.Set("q", q.Search)
+	key.Set("tag", sortedJoin(q.Tag))
+	key.Set("tags_any", sortedJoin(q.TagsAny))
+	key.Set("tags_all", sortedJoin(q.TagsAll))
+	if q.Paginated {
+		key.Set("page", strconv.Itoa(q.Page))
+		key.Set("page_size", strconv.Itoa(q.PageSize))
+	}
+	return key.Encode()
+}
+
+// newBody returns an empty response body of the shape the query answers
+// with, to decode a cached list into.
+func (q clientListQuery) newBody() interface{} {
+	if q.Paginated {
+		return &ClientPage{}
+	}
+	return &[]models.Client{}
+}
+
+// apply adds the filters of the query to a client query.
+func (q clientListQuery) apply(db, query *gorm.DB) *gorm.DB {
+	if len(q.Tag) > 0 {
+		query = query.Where("id IN (?)", clientsTaggedWith(db, q.Tag, true))
+	}
+	if len(q.TagsAny) > 0 {
+		query = query.Where("id IN (?)", clientsTaggedWith(db, q.TagsAny, false))
+	}
+	if len(q.TagsAll) > 0 {
+		query = query.Where("id IN (?)", clientsTaggedWith(db, q.TagsAll, true))
+	}
+
+	if q.Search != "" {
+		pattern := "%" + escapeLike(q.Search) + "%"
+		query = query.Where(
+			`(LOWER(name) LIKE ? ESCAPE '\' OR LOWER(slug) LIKE ? ESCAPE '\' OR LOWER(client_prefix) LIKE ? ESCAPE '\' OR LOWER(city) LIKE ? ESCAPE '\')`,
+			pattern, pattern, pattern, pattern,
+		)
+	}
+
+	return query
+}
+
+func sortedJoin(names []string) string {
+	sorted := append([]string(nil), names...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
+
+// escapeLike escapes the LIKE wildcards in s so that it matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/farellandr/fullstack2024-test/gazetteer"
+	"github.com/farellandr/fullstack2024-test/models"
+	"github.com/farellandr/fullstack2024-test/problem"
+	"github.com/farellandr/fullstack2024-test/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const maxCitySuggestions = 5
+
+// unknownCityError is returned when City does not resolve in the gazetteer.
+type unknownCityError struct {
+	City        string
+	Suggestions []string
+}
+
+func (e *unknownCityError) Error() string {
+	return fmt.Sprintf("unknown city %q", e.City)
+}
+
+// normalizeContactDetails validates and normalises the address and phone
+// fields sent in input, canonicalising City through the gazetteer. current
+// is the stored client on update and nil on create; its components fill in
+// whatever the caller did not send when the legacy Address line is
+// recomposed.
+func normalizeContactDetails(input, current *models.Client) error {
+	input.Street = strings.TrimSpace(input.Street)
+	input.District = strings.TrimSpace(input.District)
+	input.City = strings.TrimSpace(input.City)
+	input.Province = strings.TrimSpace(input.Province)
+	input.PostalCode = strings.TrimSpace(input.PostalCode)
+	input.CountryCode = strings.ToUpper(strings.TrimSpace(input.CountryCode))
+
+	if input.CountryCode != "" && !utils.IsSupportedRegion(input.CountryCode) {
+		return fmt.Errorf("unknown country code %q: use an ISO 3166-1 alpha-2 code such as ID", input.CountryCode)
+	}
+
+	merged := models.Client{}
+	if current != nil {
+		merged = *current
+	}
+	for dst, src := range map[*string]string{
+		&merged.Street:      input.Street,
+		&merged.District:    input.District,
+		&merged.City:        input.City,
+		&merged.Province:    input.Province,
+		&merged.PostalCode:  input.PostalCode,
+		&merged.CountryCode: input.CountryCode,
+	} {
+		if src != "" {
+			*dst = src
+		}
+	}
+
+	if input.City != "" {
+		// The stored country picks between cities of the same name, but
+		// unless the caller sent one, a city abroad is still found.
+		city, ok := gazetteer.Default().Lookup(input.City, merged.CountryCode)
+		if !ok && input.CountryCode == "" && merged.CountryCode != "" {
+			city, ok = gazetteer.Default().Lookup(input.City, "")
+		}
+		if !ok {
+			return &unknownCityError{
+				City:        input.City,
+				Suggestions: gazetteer.Default().Suggest(input.City, input.CountryCode, maxCitySuggestions),
+			}
+		}
+
+		// The city decides the province and country; the stored ones belong
+		// to the previous city. A country sent with it already restricted
+		// the lookup above.
+		if input.Province != "" && !strings.EqualFold(input.Province, city.Province) {
+			return fmt.Errorf("province %q does not match city %s, which is in %s", input.Province, city.Name, city.Province)
+		}
+
+		input.City, merged.City = city.Name, city.Name
+		input.Province, merged.Province = city.Province, city.Province
+		input.CountryCode, merged.CountryCode = city.CountryCode, city.CountryCode
+	}
+
+	region := merged.CountryCode
+	if region == "" {
+		region = utils.DefaultRegion()
+	}
+
+	if input.PhoneNumber != "" {
+		phone, err := utils.NormalizePhoneNumber(input.PhoneNumber, region)
+		if err != nil {
+			return fmt.Errorf("invalid phone number %q for region %s", input.PhoneNumber, region)
+		}
+		input.PhoneNumber = phone
+	}
+
+	if input.HasStructuredAddress() {
+		if merged.CountryCode == "" {
+			merged.CountryCode = region
+			input.CountryCode = region
+		}
+		if recomposesAddress(input, current) {
+			input.Address = merged.FormatAddress()
+		}
+	}
+
+	return nil
+}
+
+// recomposesAddress reports whether the Address line is rebuilt from the
+// structured components. A free-text address is kept until the caller sends
+// a street: rebuilding it from, say, a new City alone would lose the street
+// it spells out.
+func recomposesAddress(input, current *models.Client) bool {
+	switch {
+	case input.Street != "":
+		return true
+	case current == nil:
+		return input.Address == ""
+	default:
+		return current.HasStructuredAddress() || current.Address == ""
+	}
+}
+
+// respondContactError writes the 422 response for a failed
+// normalizeContactDetails call, including city suggestions when there are any.
+func respondContactError(c *gin.Context, err error) {
+	var cityErr *unknownCityError
+	if errors.As(err, &cityErr) {
+		problem.Respond(c, problem.Validation(err.Error()).With("suggestions", cityErr.Suggestions))
+		return
+	}
+
+	problem.Respond(c, problem.Validation(err.Error()))
+}
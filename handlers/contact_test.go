@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/farellandr/fullstack2024-test/models"
+	"github.com/farellandr/fullstack2024-test/tenancy"
+	"github.com/gin-gonic/gin"
+)
+
+func TestNormalizeContactDetailsAddress(t *testing.T) {
+	legacy := &models.Client{Address: "Jl. Sudirman 1, Jakarta"}
+	structured := &models.Client{Street: "Jl. Thamrin 2", City: "Jakarta", Province: "DKI Jakarta", CountryCode: "ID"}
+
+	for _, tc := range []struct {
+		name    string
+		input   models.Client
+		current *models.Client
+		want    string
+	}{
+		{"create with components", models.Client{Street: "Jl. Sudirman 1", City: "Jkt"}, nil, "Jl. Sudirman 1, Jakarta, DKI Jakarta, ID"},
+		{"create with free-text address and city", models.Client{Address: "Jl. Sudirman 1", City: "Jkt"}, nil, "Jl. Sudirman 1"},
+		{"legacy client, city only", models.Client{City: "Jkt"}, legacy, ""},
+		{"legacy client, street", models.Client{Street: "Jl. Gatot Subroto 3"}, legacy, "Jl. Gatot Subroto 3, ID"},
+		{"structured client, city only", models.Client{City: "Bandung"}, structured, "Jl. Thamrin 2, Bandung, Jawa Barat, ID"},
+		{"structured client, city and matching province", models.Client{City: "Bandung", Province: "jawa barat"}, structured, "Jl. Thamrin 2, Bandung, Jawa Barat, ID"},
+		{"structured client, city abroad", models.Client{City: "Petaling Jaya"}, structured, "Jl. Thamrin 2, Petaling Jaya, Selangor, MY"},
+		{"client without address, city only", models.Client{City: "Jkt"}, &models.Client{}, "Jakarta, DKI Jakarta, ID"},
+	} {
+		input := tc.input
+		if err := normalizeContactDetails(&input, tc.current); err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if input.Address != tc.want {
+			t.Errorf("%s: Address = %q, want %q", tc.name, input.Address, tc.want)
+		}
+	}
+
+	for _, tc := range []struct {
+		name  string
+		input models.Client
+	}{
+		{"province of another city", models.Client{City: "Bandung", Province: "DKI Jakarta"}},
+		{"city not in the country sent", models.Client{City: "Bandung", CountryCode: "MY"}},
+	} {
+		input := tc.input
+		if err := normalizeContactDetails(&input, structured); err == nil {
+			t.Errorf("%s: accepted, stored %s, %s", tc.name, input.Province, input.CountryCode)
+		}
+	}
+}
+
+func TestUpdatingCityKeepsLegacyAddress(t *testing.T) {
+	router, db := newTestRouter(t)
+	db = db.WithContext(tenancy.WithTenant(context.Background(), "alpha"))
+
+	legacy := models.Client{Name: "Acme Holdings", Slug: "acme-holdings", ClientPrefix: "ACME", Address: "Jl. Sudirman 1, Jakarta"}
+	if err := db.Create(&legacy).Error; err != nil {
+		t.Fatalf("create legacy client: %v", err)
+	}
+
+	if w := doRequest(router, http.MethodPut, "/api/v1/clients/acme-holdings", "alpha", gin.H{"City": "Jkt"}); w.Code != http.StatusOK {
+		t.Fatalf("update: got %d, body %s", w.Code, w.Body)
+	}
+
+	var stored models.Client
+	if err := db.First(&stored, legacy.ID).Error; err != nil {
+		t.Fatalf("reload client: %v", err)
+	}
+	if stored.Address != legacy.Address {
+		t.Errorf("Address = %q after updating City, want %q", stored.Address, legacy.Address)
+	}
+	if stored.City != "Jakarta" {
+		t.Errorf("City = %q, want Jakarta", stored.City)
+	}
+}
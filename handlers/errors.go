@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/farellandr/fullstack2024-test/problem"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// respondClientLookupError reports a failed lookup of the client named in
+// the URL.
+func respondClientLookupError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		problem.Respond(c, problem.NotFound("Client not found"))
+		return
+	}
+	problem.Respond(c, problem.Internal("Failed to retrieve client", err))
+}
@@ -0,0 +1,414 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/farellandr/fullstack2024-test/models"
+	"github.com/farellandr/fullstack2024-test/problem"
+	"github.com/farellandr/fullstack2024-test/tenancy"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxHierarchyDepth is the number of levels a hierarchy may have below its
+// root. It also bounds the recursive queries so a corrupted hierarchy can
+// never make them run away; they fail rather than return part of it.
+const maxHierarchyDepth = 64
+
+var (
+	errParentNotFound   = errors.New("parent client not found")
+	errHierarchyCycle   = errors.New("parent client would create a cycle in the hierarchy")
+	errHierarchyTooDeep = fmt.Errorf("client hierarchy cannot be deeper than %d levels", maxHierarchyDepth)
+	errHasChildren      = errors.New("client has child clients")
+)
+
+// ClientTreeNode is a client together with its descendants.
+type ClientTreeNode struct {
+	models.Client
+	Children []*ClientTreeNode
+}
+
+// GetClientChildren godoc
+// @Summary List child clients
+// @Description Lists the direct subsidiaries of a client.
+// @Tags hierarchy
+// @Produce json
+// @Param slug path string true "The unique slug of the parent client"
+// @Success 200 {array} models.Client "The direct children"
+// @Failure 404 {object} problem.Problem "Client not found"
+// @Failure 500 {object} problem.Problem "Failed to retrieve children"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /clients/{slug}/children [get]
+func (h *ClientHandler) GetClientChildren(c *gin.Context) {
+	slug := c.Param("slug")
+	var client models.Client
+
+	if err := h.db(c).Where("slug = ?", slug).First(&client).Error; err != nil {
+		respondClientLookupError(c, err)
+		return
+	}
+
+	children := []models.Client{}
+	if err := h.db(c).Preload("Tags").Where("parent_id = ?", client.ID).Order("name").Find(&children).Error; err != nil {
+		problem.Respond(c, problem.Internal("Failed to retrieve children", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, children)
+}
+
+// GetClientAncestors godoc
+// @Summary List client ancestors
+// @Description Lists the ancestor path of a client, from the top-level holding company down to its direct parent.
+// @Tags hierarchy
+// @Produce json
+// @Param slug path string true "The unique slug of the client"
+// @Success 200 {array} models.Client "The ancestors, root first"
+// @Failure 404 {object} problem.Problem "Client not found"
+// @Failure 409 {object} problem.Problem "Hierarchy deeper than the supported limit"
+// @Failure 500 {object} problem.Problem "Failed to retrieve ancestors"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /clients/{slug}/ancestors [get]
+func (h *ClientHandler) GetClientAncestors(c *gin.Context) {
+	slug := c.Param("slug")
+	var client models.Client
+
+	if err := h.db(c).Where("slug = ?", slug).First(&client).Error; err != nil {
+		respondClientLookupError(c, err)
+		return
+	}
+
+	ids, err := ancestorIDs(h.db(c), client.ID)
+	if err != nil {
+		respondHierarchyError(c, "Failed to retrieve ancestors", err)
+		return
+	}
+
+	ancestors, err := clientsInOrder(h.db(c), ids)
+	if err != nil {
+		problem.Respond(c, problem.Internal("Failed to retrieve ancestors", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, ancestors)
+}
+
+// GetClientTree godoc
+// @Summary Get client tree
+// @Description Returns a client with all of its descendants nested under Children.
+// @Tags hierarchy
+// @Produce json
+// @Param slug path string true "The unique slug of the root client"
+// @Success 200 {object} ClientTreeNode "The client tree"
+// @Failure 404 {object} problem.Problem "Client not found"
+// @Failure 409 {object} problem.Problem "Hierarchy deeper than the supported limit"
+// @Failure 500 {object} problem.Problem "Failed to retrieve client tree"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /clients/{slug}/tree [get]
+func (h *ClientHandler) GetClientTree(c *gin.Context) {
+	slug := c.Param("slug")
+	var client models.Client
+
+	if err := h.db(c).Where("slug = ?", slug).First(&client).Error; err != nil {
+		respondClientLookupError(c, err)
+		return
+	}
+
+	ids, err := descendantIDs(h.db(c), client.ID)
+	if err != nil {
+		respondHierarchyError(c, "Failed to retrieve client tree", err)
+		return
+	}
+
+	var clients []models.Client
+	if err := h.db(c).Preload("Tags").Where("id IN ?", ids).Order("name").Find(&clients).Error; err != nil {
+		problem.Respond(c, problem.Internal("Failed to retrieve client tree", err))
+		return
+	}
+
+	nodes := make(map[uint]*ClientTreeNode, len(clients))
+	for _, cl := range clients {
+		nodes[cl.ID] = &ClientTreeNode{Client: cl, Children: []*ClientTreeNode{}}
+	}
+	for _, cl := range clients {
+		if cl.ID == client.ID || cl.ParentID == nil {
+			continue
+		}
+		if parent, ok := nodes[*cl.ParentID]; ok {
+			parent.Children = append(parent.Children, nodes[cl.ID])
+		}
+	}
+
+	root, ok := nodes[client.ID]
+	if !ok {
+		problem.Respond(c, problem.NotFound("Client not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, root)
+}
+
+// DetachClientParent godoc
+// @Summary Detach a client from its parent
+// @Description Makes a client top-level by clearing its parent reference, and refreshes the cache.
+// @Tags hierarchy
+// @Produce json
+// @Param slug path string true "The unique slug of the client"
+// @Success 200 {object} models.Client "The detached client"
+// @Failure 404 {object} problem.Problem "Client not found"
+// @Failure 500 {object} problem.Problem "Failed to detach client"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /clients/{slug}/parent [delete]
+func (h *ClientHandler) DetachClientParent(c *gin.Context) {
+	slug := c.Param("slug")
+	var client models.Client
+
+	if err := h.db(c).Where("slug = ?", slug).First(&client).Error; err != nil {
+		respondClientLookupError(c, err)
+		return
+	}
+
+	if err := h.db(c).Model(&client).Update("parent_id", nil).Error; err != nil {
+		problem.Respond(c, problem.Internal("Failed to detach client", err))
+		return
+	}
+
+	h.refreshClientCache(c, &client)
+
+	c.JSON(http.StatusOK, client)
+}
+
+// validateParent checks that parentID names a live client which may become
+// the parent of clientID. A zero clientID stands for a client not yet created.
+//
+// It first locks the client, the parent and the parent's ancestors, so db
+// must be the transaction that applies the change: concurrent reparents
+// touching the same chain then run one after the other, and the second sees
+// the first when it looks for a cycle, while a concurrent delete of the
+// parent or of an ancestor makes the parent not found.
+func validateParent(db *gorm.DB, clientID, parentID uint) error {
+	if clientID != 0 && clientID == parentID {
+		return errHierarchyCycle
+	}
+
+	locked := []uint{parentID}
+	if clientID != 0 {
+		locked = append(locked, clientID)
+	}
+	ancestors, err := lockHierarchy(db, locked, func() ([]uint, error) {
+		return ancestorIDs(db, parentID)
+	})
+	if err != nil {
+		return err
+	}
+
+	var parent models.Client
+	if err := db.Select("id").First(&parent, parentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errParentNotFound
+		}
+		return err
+	}
+
+	height := 0
+	if clientID != 0 {
+		for _, id := range ancestors {
+			if id == clientID {
+				return errHierarchyCycle
+			}
+		}
+
+		subtree, err := descendants(db, clientID)
+		if err != nil {
+			return err
+		}
+		for _, node := range subtree {
+			height = max(height, node.Depth)
+		}
+	}
+
+	if len(ancestors)+1+height > maxHierarchyDepth {
+		return errHierarchyTooDeep
+	}
+
+	return nil
+}
+
+// lockHierarchy locks the clients in ids and those returned by load, and
+// returns the latter. Since they may change while it waits for the locks,
+// load is repeated until every client it returns is locked.
+func lockHierarchy(db *gorm.DB, ids []uint, load func() ([]uint, error)) ([]uint, error) {
+	locked := make(map[uint]bool)
+	for {
+		loaded, err := load()
+		if err != nil {
+			return nil, err
+		}
+
+		var pending []uint
+		for _, id := range append(ids, loaded...) {
+			if !locked[id] {
+				locked[id] = true
+				pending = append(pending, id)
+			}
+		}
+		if len(pending) == 0 {
+			return loaded, nil
+		}
+		if err := lockClients(db, pending); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// lockClients takes row locks on the given clients until the transaction
+// ends. SQLite has no row locks and serialises writers instead.
+func lockClients(db *gorm.DB, ids []uint) error {
+	var locked []uint
+	return db.Model(&models.Client{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ?", ids).Order("id").Pluck("id", &locked).Error
+}
+
+// hierarchyNode is a client found by a recursive query, with its distance
+// from the client the query started at.
+type hierarchyNode struct {
+	ID    uint
+	Depth int
+}
+
+// ancestorIDs returns the IDs of the live ancestors of a client, root first.
+// Raw SQL escapes the tenant callbacks, so the tenant is filtered explicitly.
+func ancestorIDs(db *gorm.DB, clientID uint) ([]uint, error) {
+	tenant, ok := tenancy.FromContext(db.Statement.Context)
+	if !ok {
+		return nil, tenancy.ErrMissingTenant
+	}
+
+	// The query walks one level past the limit, so that a deeper hierarchy
+	// is reported instead of silently cut short.
+	var path []hierarchyNode
+	err := db.Raw(`
+		WITH RECURSIVE path AS (
+			SELECT id, parent_id, 0 AS depth FROM my_client WHERE id = ? AND tenant_id = ?
+			UNION ALL
+			SELECT c.id, c.parent_id, path.depth + 1
+			FROM my_client c JOIN path ON c.id = path.parent_id
+			WHERE c.deleted_at IS NULL AND c.tenant_id = ? AND path.depth <= ?
+		)
+		SELECT id, depth FROM path WHERE depth > 0 ORDER BY depth DESC`,
+		clientID, tenant, tenant, maxHierarchyDepth).Scan(&path).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(path) > maxHierarchyDepth {
+		return nil, errHierarchyTooDeep
+	}
+
+	ids := make([]uint, len(path))
+	for i, node := range path {
+		ids[i] = node.ID
+	}
+	return ids, nil
+}
+
+// descendantIDs returns the IDs of a live client and all of its live descendants.
+func descendantIDs(db *gorm.DB, clientID uint) ([]uint, error) {
+	tree, err := descendants(db, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, len(tree))
+	for i, node := range tree {
+		ids[i] = node.ID
+	}
+	return ids, nil
+}
+
+// descendants returns a live client and all of its live descendants, with
+// their depth below it.
+func descendants(db *gorm.DB, clientID uint) ([]hierarchyNode, error) {
+	tenant, ok := tenancy.FromContext(db.Statement.Context)
+	if !ok {
+		return nil, tenancy.ErrMissingTenant
+	}
+
+	var tree []hierarchyNode
+	err := db.Raw(`
+		WITH RECURSIVE tree AS (
+			SELECT id, 0 AS depth FROM my_client WHERE id = ? AND tenant_id = ? AND deleted_at IS NULL
+			UNION ALL
+			SELECT c.id, tree.depth + 1
+			FROM my_client c JOIN tree ON c.parent_id = tree.id
+			WHERE c.deleted_at IS NULL AND c.tenant_id = ? AND tree.depth <= ?
+		)
+		SELECT id, depth FROM tree`,
+		clientID, tenant, tenant, maxHierarchyDepth).Scan(&tree).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range tree {
+		if node.Depth > maxHierarchyDepth {
+			return nil, errHierarchyTooDeep
+		}
+	}
+
+	return tree, nil
+}
+
+// clientsInOrder loads the given clients, keeping the order of ids.
+func clientsInOrder(db *gorm.DB, ids []uint) ([]models.Client, error) {
+	ordered := make([]models.Client, 0, len(ids))
+	if len(ids) == 0 {
+		return ordered, nil
+	}
+
+	var clients []models.Client
+	if err := db.Preload("Tags").Where("id IN ?", ids).Find(&clients).Error; err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uint]models.Client, len(clients))
+	for _, cl := range clients {
+		byID[cl.ID] = cl
+	}
+	for _, id := range ids {
+		if cl, ok := byID[id]; ok {
+			ordered = append(ordered, cl)
+		}
+	}
+
+	return ordered, nil
+}
+
+// isParentError reports whether err is a validateParent verdict rather than
+// a database failure.
+func isParentError(err error) bool {
+	return errors.Is(err, errParentNotFound) || errors.Is(err, errHierarchyCycle) || errors.Is(err, errHierarchyTooDeep)
+}
+
+// respondParentError writes the response for a failed validateParent call.
+func respondParentError(c *gin.Context, err error) {
+	if isParentError(err) {
+		problem.Respond(c, problem.Validation(err.Error()))
+		return
+	}
+	problem.Respond(c, problem.Internal("Failed to validate parent client", err))
+}
+
+// respondHierarchyError writes the response for a failed recursive query:
+// a conflict for a hierarchy too deep to walk, title otherwise.
+func respondHierarchyError(c *gin.Context, title string, err error) {
+	if errors.Is(err, errHierarchyTooDeep) {
+		problem.Respond(c, problem.Conflict(err.Error()))
+		return
+	}
+	problem.Respond(c, problem.Internal(title, err))
+}
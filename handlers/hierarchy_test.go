@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/farellandr/fullstack2024-test/models"
+	"github.com/farellandr/fullstack2024-test/tenancy"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// createChain stores n clients in tenant alpha, each the parent of the next,
+// and returns them root first.
+func createChain(t *testing.T, db *gorm.DB, n int) []models.Client {
+	t.Helper()
+
+	chain := make([]models.Client, n)
+	for i := range chain {
+		chain[i] = models.Client{Name: fmt.Sprintf("Level %d", i), Slug: fmt.Sprintf("level-%d", i), ClientPrefix: fmt.Sprintf("L%03d", i)}
+		if i > 0 {
+			chain[i].ParentID = &chain[i-1].ID
+		}
+		if err := db.Create(&chain[i]).Error; err != nil {
+			t.Fatalf("create level %d: %v", i, err)
+		}
+	}
+	return chain
+}
+
+func TestReparentingRejectsCycles(t *testing.T) {
+	router, db := newTestRouter(t)
+	chain := createChain(t, db.WithContext(tenancy.WithTenant(context.Background(), "alpha")), 3)
+
+	w := doRequest(router, http.MethodPut, "/api/v1/clients/level-0", "alpha", gin.H{"ParentID": chain[2].ID})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("reparent root under its grandchild: got %d, want 422, body %s", w.Code, w.Body)
+	}
+
+	w = doRequest(router, http.MethodPut, "/api/v1/clients/level-2", "alpha", gin.H{"ParentID": chain[0].ID})
+	if w.Code != http.StatusOK {
+		t.Fatalf("reparent grandchild under root: got %d, body %s", w.Code, w.Body)
+	}
+	w = doRequest(router, http.MethodPut, "/api/v1/clients/level-1", "alpha", gin.H{"ParentID": chain[2].ID})
+	if w.Code != http.StatusOK {
+		t.Errorf("reparent under former child: got %d, body %s", w.Code, w.Body)
+	}
+}
+
+func TestHierarchyDepthLimit(t *testing.T) {
+	router, db := newTestRouter(t)
+	db = db.WithContext(tenancy.WithTenant(context.Background(), "alpha"))
+	chain := createChain(t, db, maxHierarchyDepth+1)
+	deepest := chain[maxHierarchyDepth]
+
+	if w := doRequest(router, http.MethodGet, "/api/v1/clients/"+deepest.Slug+"/ancestors", "alpha", nil); w.Code != http.StatusOK {
+		t.Errorf("ancestors at the depth limit: got %d, body %s", w.Code, w.Body)
+	}
+
+	w := doRequest(router, http.MethodPost, "/api/v1/clients", "alpha", gin.H{"Name": "Too Deep", "City": "Jkt", "ParentID": deepest.ID})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("create below the depth limit: got %d, want 422, body %s", w.Code, w.Body)
+	}
+
+	loose := models.Client{Name: "Loose", Slug: "loose", ClientPrefix: "LOSE"}
+	if err := db.Create(&loose).Error; err != nil {
+		t.Fatalf("create loose client: %v", err)
+	}
+	w = doRequest(router, http.MethodPut, "/api/v1/clients/loose", "alpha", gin.H{"ParentID": deepest.ID})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("reparent below the depth limit: got %d, want 422, body %s", w.Code, w.Body)
+	}
+	w = doRequest(router, http.MethodPut, "/api/v1/clients/level-0", "alpha", gin.H{"ParentID": loose.ID})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("reparent a full-depth subtree: got %d, want 422, body %s", w.Code, w.Body)
+	}
+
+	// A hierarchy deeper than the limit, e.g. written before it existed, is
+	// reported rather than returned in part.
+	if err := db.Model(&loose).Update("parent_id", deepest.ID).Error; err != nil {
+		t.Fatalf("attach loose client: %v", err)
+	}
+	for _, path := range []string{"/api/v1/clients/loose/ancestors", "/api/v1/clients/level-0/tree"} {
+		if w := doRequest(router, http.MethodGet, path, "alpha", nil); w.Code != http.StatusConflict {
+			t.Errorf("GET %s beyond the depth limit: got %d, want 409", path, w.Code)
+		}
+	}
+	if w := doRequest(router, http.MethodDelete, "/api/v1/clients/level-0?cascade=true", "alpha", nil); w.Code != http.StatusConflict {
+		t.Errorf("cascade delete beyond the depth limit: got %d, want 409", w.Code)
+	}
+}
+
+func TestDeletingClientsWithChildren(t *testing.T) {
+	router, db := newTestRouter(t)
+	db = db.WithContext(tenancy.WithTenant(context.Background(), "alpha"))
+	chain := createChain(t, db, 3)
+
+	if w := doRequest(router, http.MethodDelete, "/api/v1/clients/level-1", "alpha", nil); w.Code != http.StatusConflict {
+		t.Fatalf("delete with children: got %d, want 409, body %s", w.Code, w.Body)
+	}
+	if err := db.First(&models.Client{}, chain[1].ID).Error; err != nil {
+		t.Fatalf("client deleted despite its children: %v", err)
+	}
+
+	if w := doRequest(router, http.MethodDelete, "/api/v1/clients/level-1?cascade=true", "alpha", nil); w.Code != http.StatusOK {
+		t.Fatalf("cascade delete: got %d, body %s", w.Code, w.Body)
+	}
+	var left []string
+	if err := db.Model(&models.Client{}).Order("id").Pluck("slug", &left).Error; err != nil {
+		t.Fatalf("list clients: %v", err)
+	}
+	if fmt.Sprint(left) != "[level-0]" {
+		t.Errorf("after cascade delete: clients %v, want [level-0]", left)
+	}
+
+	w := doRequest(router, http.MethodPost, "/api/v1/clients", "alpha", gin.H{"Name": "Orphan", "City": "Jkt", "ParentID": chain[2].ID})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("create under a deleted parent: got %d, want 422, body %s", w.Code, w.Body)
+	}
+	if w := doRequest(router, http.MethodDelete, "/api/v1/clients/level-1", "alpha", nil); w.Code != http.StatusNotFound {
+		t.Errorf("delete a deleted client: got %d, want 404", w.Code)
+	}
+}
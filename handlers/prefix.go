@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/farellandr/fullstack2024-test/models"
+	"github.com/farellandr/fullstack2024-test/problem"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	clientPrefixLength     = 4
+	maxPrefixAttempts      = 3
+	defaultPrefixSuggested = 5
+	maxPrefixSuggested     = 20
+)
+
+var errNoFreePrefix = errors.New("no free client prefix could be derived from the name")
+
+// Legal-form words that carry no meaning for a prefix.
+var prefixStopWords = map[string]bool{
+	"PT": true, "CV": true, "TBK": true, "UD": true, "PD": true,
+	"PTE": true, "LTD": true, "SDN": true, "BHD": true, "INC": true,
+	"CO": true, "CORP": true, "LLC": true, "THE": true, "AND": true,
+}
+
+// PrefixSuggestionsResponse lists unused prefixes for a client name.
+type PrefixSuggestionsResponse struct {
+	Name        string
+	Suggestions []string
+}
+
+// GetPrefixSuggestions godoc
+// @Summary Suggest client prefixes
+// @Description Proposes unused ClientPrefix values derived from a client name, best first.
+// @Tags clients
+// @Produce json
+// @Param name query string true "The client name to derive prefixes from"
+// @Param limit query int false "Maximum number of suggestions (default 5, max 20)"
+// @Success 200 {object} PrefixSuggestionsResponse "Free prefixes"
+// @Failure 400 {object} problem.Problem "Missing name or invalid limit"
+// @Failure 500 {object} problem.Problem "Failed to suggest prefixes"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /clients/prefix-suggestions [get]
+func (h *ClientHandler) GetPrefixSuggestions(c *gin.Context) {
+	name := strings.TrimSpace(c.Query("name"))
+	if name == "" {
+		problem.Respond(c, problem.BadRequest("name is required"))
+		return
+	}
+
+	limit := defaultPrefixSuggested
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxPrefixSuggested {
+			problem.Respond(c, problem.BadRequest(fmt.Sprintf("limit must be between 1 and %d", maxPrefixSuggested)))
+			return
+		}
+		limit = n
+	}
+
+	suggestions, err := freePrefixes(h.db(c), name, limit)
+	if err != nil {
+		problem.Respond(c, problem.Internal("Failed to suggest prefixes", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, PrefixSuggestionsResponse{Name: name, Suggestions: suggestions})
+}
+
+// allocatePrefix returns the best unused prefix for a client name.
+func allocatePrefix(db *gorm.DB, name string) (string, error) {
+	free, err := freePrefixes(db, name, 1)
+	if err != nil {
+		return "", err
+	}
+	if len(free) == 0 {
+		return "", errNoFreePrefix
+	}
+	return free[0], nil
+}
+
+// freePrefixes returns up to limit candidate prefixes for name that no live
+// client of the tenant uses yet, in candidate order.
+func freePrefixes(db *gorm.DB, name string, limit int) ([]string, error) {
+	candidates := prefixCandidates(name)
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	var taken []string
+	if err := db.Model(&models.Client{}).Where("client_prefix IN ?", candidates).Pluck("client_prefix", &taken).Error; err != nil {
+		return nil, err
+	}
+
+	used := make(map[string]bool, len(taken))
+	for _, p := range taken {
+		used[p] = true
+	}
+
+	free := make([]string, 0, limit)
+	for _, p := range candidates {
+		if !used[p] {
+			free = append(free, p)
+			if len(free) == limit {
+				break
+			}
+		}
+	}
+
+	return free, nil
+}
+
+// prefixCandidates derives prefixes from a client name, most natural first:
+// word initials, then consonants, then leading letters, and finally the best
+// of those with numeric suffixes. The result is deterministic for a name.
+func prefixCandidates(name string) []string {
+	words := prefixWords(name)
+	if len(words) == 0 {
+		return nil
+	}
+
+	joined := strings.Join(words, "")
+	var bases []string
+
+	// Initials, topped up from the last word when there are too few words.
+	var initials strings.Builder
+	for _, w := range words {
+		initials.WriteByte(w[0])
+	}
+	last := words[len(words)-1]
+	bases = append(bases, initials.String()+consonants(last[1:])+last[1:])
+
+	// First letter followed by the consonants of the whole name.
+	bases = append(bases, joined[:1]+consonants(joined[1:]))
+
+	// Leading letters of the name.
+	bases = append(bases, joined)
+
+	seen := make(map[string]bool)
+	var candidates []string
+	add := func(p string) {
+		if len(p) == clientPrefixLength && !seen[p] {
+			seen[p] = true
+			candidates = append(candidates, p)
+		}
+	}
+
+	for _, b := range bases {
+		if len(b) >= clientPrefixLength {
+			add(b[:clientPrefixLength])
+		}
+	}
+
+	stem := joined + strings.Repeat("X", clientPrefixLength)
+	if len(candidates) > 0 {
+		stem = candidates[0]
+	}
+	for i := 1; i <= 9; i++ {
+		add(stem[:3] + strconv.Itoa(i))
+	}
+	for i := 1; i <= 99; i++ {
+		add(fmt.Sprintf("%s%02d", stem[:2], i))
+	}
+
+	return candidates
+}
+
+// prefixWords splits a name into upper-case alphanumeric words, dropping
+// legal-form words unless nothing else is left.
+func prefixWords(name string) []string {
+	fields := strings.FieldsFunc(strings.ToUpper(name), func(r rune) bool {
+		return !((r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'))
+	})
+
+	var words []string
+	for _, f := range fields {
+		if !prefixStopWords[f] {
+			words = append(words, f)
+		}
+	}
+	if len(words) == 0 {
+		return fields
+	}
+
+	return words
+}
+
+func consonants(s string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune("AEIOU", r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// normalizeClientPrefix upper-cases a caller-supplied prefix and checks its shape.
+func normalizeClientPrefix(prefix string) (string, error) {
+	prefix = strings.ToUpper(strings.TrimSpace(prefix))
+	if len(prefix) != clientPrefixLength {
+		return "", fmt.Errorf("client prefix must be exactly %d characters", clientPrefixLength)
+	}
+	for _, r := range prefix {
+		if !((r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')) {
+			return "", errors.New("client prefix may only contain letters and digits")
+		}
+	}
+	return prefix, nil
+}
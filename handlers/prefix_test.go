@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/farellandr/fullstack2024-test/models"
+	"github.com/farellandr/fullstack2024-test/tenancy"
+	"github.com/gin-gonic/gin"
+)
+
+func TestPrefixCandidates(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		first []string
+		last  string
+		count int
+	}{
+		{"Acme Holdings", []string{"AHLD", "ACMH", "ACME", "AHL1"}, "AH99", 111},
+		{"PT Sinar Jaya Tbk", []string{"SJYA", "SNRJ", "SINA", "SJY1"}, "SJ99", 111},
+		// Names shorter than a prefix are padded, leaving only suffixed candidates.
+		{"PT", []string{"PTX1", "PTX2"}, "PT99", 108},
+		{"Jo", []string{"JOX1", "JOX2"}, "JO99", 108},
+	} {
+		candidates := prefixCandidates(tc.name)
+		if len(candidates) != tc.count {
+			t.Errorf("%s: got %d candidates, want %d", tc.name, len(candidates), tc.count)
+			continue
+		}
+		if got := candidates[:len(tc.first)]; fmt.Sprint(got) != fmt.Sprint(tc.first) {
+			t.Errorf("%s: candidates start %v, want %v", tc.name, got, tc.first)
+		}
+		if got := candidates[len(candidates)-1]; got != tc.last {
+			t.Errorf("%s: last candidate %s, want %s", tc.name, got, tc.last)
+		}
+
+		seen := make(map[string]bool)
+		for _, p := range candidates {
+			if len(p) != clientPrefixLength {
+				t.Errorf("%s: candidate %q is not %d characters", tc.name, p, clientPrefixLength)
+			}
+			if seen[p] {
+				t.Errorf("%s: candidate %q repeated", tc.name, p)
+			}
+			seen[p] = true
+		}
+	}
+
+	if candidates := prefixCandidates("--- & ---"); candidates != nil {
+		t.Errorf("name without letters: got %v, want none", candidates)
+	}
+}
+
+func TestAllocatePrefixSkipsTakenAndRunsOut(t *testing.T) {
+	_, db := newTestRouter(t)
+	db = db.WithContext(tenancy.WithTenant(context.Background(), "alpha"))
+
+	const name = "Acme Holdings"
+	candidates := prefixCandidates(name)
+
+	take := func(i int, prefix string) {
+		t.Helper()
+		client := models.Client{Name: name, Slug: fmt.Sprintf("acme-%d", i), ClientPrefix: prefix}
+		if err := db.Create(&client).Error; err != nil {
+			t.Fatalf("create client with prefix %s: %v", prefix, err)
+		}
+	}
+
+	take(0, candidates[0])
+	prefix, err := allocatePrefix(db, name)
+	if err != nil || prefix != candidates[1] {
+		t.Fatalf("with %s taken: got %q, %v, want %s", candidates[0], prefix, err, candidates[1])
+	}
+
+	// Prefixes of other tenants and of deleted clients are free.
+	other := models.Client{Name: name, Slug: "acme-beta", ClientPrefix: candidates[1]}
+	if err := db.WithContext(tenancy.WithTenant(context.Background(), "beta")).Create(&other).Error; err != nil {
+		t.Fatalf("create client in other tenant: %v", err)
+	}
+	if prefix, _ := allocatePrefix(db, name); prefix != candidates[1] {
+		t.Errorf("prefix taken in other tenant: got %q, want %s", prefix, candidates[1])
+	}
+
+	for i, p := range candidates[1:] {
+		take(i+1, p)
+	}
+	if prefix, err := allocatePrefix(db, name); !errors.Is(err, errNoFreePrefix) {
+		t.Errorf("every candidate taken: got %q, %v, want errNoFreePrefix", prefix, err)
+	}
+
+	if err := db.Where("client_prefix = ?", candidates[5]).Delete(&models.Client{}).Error; err != nil {
+		t.Fatalf("delete client: %v", err)
+	}
+	if prefix, err := allocatePrefix(db, name); err != nil || prefix != candidates[5] {
+		t.Errorf("after deleting the holder of %s: got %q, %v", candidates[5], prefix, err)
+	}
+}
+
+func TestCreateClientAllocatesDistinctPrefixes(t *testing.T) {
+	router, _ := newTestRouter(t)
+
+	seen := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		w := doRequest(router, http.MethodPost, "/api/v1/clients", "alpha", gin.H{"Name": "Acme Holdings", "City": "Jkt"})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("create %d: got %d, body %s", i, w.Code, w.Body)
+		}
+
+		var created models.Client
+		if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+			t.Fatalf("decode created client: %v", err)
+		}
+		if seen[created.ClientPrefix] {
+			t.Errorf("create %d: prefix %s allocated twice", i, created.ClientPrefix)
+		}
+		seen[created.ClientPrefix] = true
+	}
+
+	w := doRequest(router, http.MethodPost, "/api/v1/clients", "alpha", gin.H{"Name": "Other Co", "City": "Jkt", "ClientPrefix": "AHLD"})
+	if w.Code != http.StatusConflict {
+		t.Errorf("explicit prefix already in use: got %d, want 409", w.Code)
+	}
+}
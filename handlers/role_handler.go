@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/farellandr/fullstack2024-test/auth"
+	"github.com/farellandr/fullstack2024-test/models"
+	"github.com/farellandr/fullstack2024-test/problem"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RoleHandler struct {
+	DB    *gorm.DB
+	Roles *auth.RoleStore
+}
+
+func NewRoleHandler(db *gorm.DB, roles *auth.RoleStore) *RoleHandler {
+	return &RoleHandler{
+		DB:    db,
+		Roles: roles,
+	}
+}
+
+// db returns the database handle for a request, bound to its context. Roles
+// are shared by all tenants; role assignments are scoped to the tenant of
+// the request.
+func (h *RoleHandler) db(c *gin.Context) *gorm.DB {
+	return h.DB.WithContext(c.Request.Context())
+}
+
+type RoleRequest struct {
+	Description string   `json:"description" binding:"max=255"`
+	Permissions []string `json:"permissions" binding:"required,min=1"`
+}
+
+type RoleAssignmentRequest struct {
+	Subject string `json:"subject" binding:"required,max=255"`
+	Role    string `json:"role" binding:"required"`
+}
+
+// GetRoles godoc
+// @Summary List roles
+// @Description Lists every role with its permissions.
+// @Tags roles
+// @Produce json
+// @Success 200 {array} models.Role "All roles"
+// @Failure 500 {object} problem.Problem "Failed to retrieve roles"
+// @Security BearerAuth
+// @Router /admin/roles [get]
+func (h *RoleHandler) GetRoles(c *gin.Context) {
+	roles := []models.Role{}
+
+	if err := h.db(c).Order("name").Find(&roles).Error; err != nil {
+		problem.Respond(c, problem.Internal("Failed to retrieve roles", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, roles)
+}
+
+// PutRole godoc
+// @Summary Create or update a role
+// @Description Creates a role or replaces the permissions of an existing one. Roles are shared by all tenants, so this requires the tenants:all permission. The admin role cannot be changed.
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Param name path string true "The role name"
+// @Param role body RoleRequest true "Role description and permissions"
+// @Success 200 {object} models.Role "The stored role"
+// @Failure 400 {object} problem.Problem "Invalid request payload"
+// @Failure 403 {object} problem.Problem "The admin role is built in, or the caller is not a cross-tenant administrator"
+// @Failure 422 {object} problem.Problem "Unknown permission"
+// @Failure 500 {object} problem.Problem "Failed to save role"
+// @Security BearerAuth
+// @Router /admin/roles/{name} [put]
+func (h *RoleHandler) PutRole(c *gin.Context) {
+	if !auth.CrossTenant(c) {
+		problem.Respond(c, problem.Forbidden("Roles are shared by all tenants; changing them requires "+auth.PermTenantsAll))
+		return
+	}
+
+	name := strings.ToLower(strings.TrimSpace(c.Param("name")))
+	if name == auth.RoleAdmin {
+		problem.Respond(c, problem.Forbidden("The admin role is built in and cannot be changed"))
+		return
+	}
+
+	var req RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Respond(c, problem.InvalidBody(err))
+		return
+	}
+
+	for _, perm := range req.Permissions {
+		if !auth.IsKnownPermission(perm) {
+			problem.Respond(c, problem.Validation("Unknown permission: "+perm))
+			return
+		}
+	}
+
+	role := models.Role{Name: name, Description: req.Description, Permissions: req.Permissions}
+	err := h.db(c).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"description", "permissions", "updated_at"}),
+	}).Create(&role).Error
+	if err != nil {
+		problem.Respond(c, problem.Internal("Failed to save role", err))
+		return
+	}
+
+	if err := h.db(c).Where("name = ?", name).First(&role).Error; err != nil {
+		problem.Respond(c, problem.Internal("Failed to save role", err))
+		return
+	}
+
+	h.Roles.Invalidate("")
+
+	c.JSON(http.StatusOK, role)
+}
+
+// GetRoleAssignments godoc
+// @Summary List role assignments
+// @Description Lists the role assignments of the caller's tenant, optionally for a single subject.
+// @Tags roles
+// @Produce json
+// @Param subject query string false "Only assignments of this subject"
+// @Success 200 {array} models.RoleAssignment "Role assignments"
+// @Failure 500 {object} problem.Problem "Failed to retrieve role assignments"
+// @Security BearerAuth
+// @Router /admin/role-assignments [get]
+func (h *RoleHandler) GetRoleAssignments(c *gin.Context) {
+	assignments := []models.RoleAssignment{}
+
+	query := h.db(c).Preload("Role").Order("subject, id")
+	if subject := c.Query("subject"); subject != "" {
+		query = query.Where("subject = ?", subject)
+	}
+
+	if err := query.Find(&assignments).Error; err != nil {
+		problem.Respond(c, problem.Internal("Failed to retrieve role assignments", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, assignments)
+}
+
+// CreateRoleAssignment godoc
+// @Summary Assign a role
+// @Description Assigns a role to a token subject in the caller's tenant. Assigning a role the subject already holds is a no-op.
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Param assignment body RoleAssignmentRequest true "Subject and role name"
+// @Success 201 {object} models.RoleAssignment "The assignment"
+// @Failure 400 {object} problem.Problem "Invalid request payload"
+// @Failure 422 {object} problem.Problem "Unknown role"
+// @Failure 500 {object} problem.Problem "Failed to assign role"
+// @Security BearerAuth
+// @Router /admin/role-assignments [post]
+func (h *RoleHandler) CreateRoleAssignment(c *gin.Context) {
+	var req RoleAssignmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Respond(c, problem.InvalidBody(err))
+		return
+	}
+
+	var role models.Role
+	if err := h.db(c).Where("name = ?", strings.ToLower(req.Role)).First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			problem.Respond(c, problem.Validation("Unknown role: "+req.Role))
+		} else {
+			problem.Respond(c, problem.Internal("Failed to assign role", err))
+		}
+		return
+	}
+
+	assignment := models.RoleAssignment{Subject: req.Subject, RoleID: role.ID, CreatedBy: auth.Subject(c)}
+	err := h.db(c).Clauses(clause.OnConflict{DoNothing: true}).Create(&assignment).Error
+	if err == nil {
+		err = h.db(c).Preload("Role").Where("subject = ? AND role_id = ?", req.Subject, role.ID).First(&assignment).Error
+	}
+	if err != nil {
+		problem.Respond(c, problem.Internal("Failed to assign role", err))
+		return
+	}
+
+	h.Roles.Invalidate(req.Subject)
+
+	c.JSON(http.StatusCreated, assignment)
+}
+
+// DeleteRoleAssignment godoc
+// @Summary Remove a role assignment
+// @Description Takes a role away from a subject in the caller's tenant.
+// @Tags roles
+// @Produce json
+// @Param id path int true "The role assignment ID"
+// @Success 200 {object} map[string]string "Role assignment removed"
+// @Failure 404 {object} problem.Problem "Role assignment not found"
+// @Failure 500 {object} problem.Problem "Failed to remove role assignment"
+// @Security BearerAuth
+// @Router /admin/role-assignments/{id} [delete]
+func (h *RoleHandler) DeleteRoleAssignment(c *gin.Context) {
+	var assignment models.RoleAssignment
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		problem.Respond(c, problem.NotFound("Role assignment not found"))
+		return
+	}
+
+	if err := h.db(c).First(&assignment, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			problem.Respond(c, problem.NotFound("Role assignment not found"))
+		} else {
+			problem.Respond(c, problem.Internal("Failed to retrieve role assignment", err))
+		}
+		return
+	}
+
+	if err := h.db(c).Delete(&assignment).Error; err != nil {
+		problem.Respond(c, problem.Internal("Failed to remove role assignment", err))
+		return
+	}
+
+	h.Roles.Invalidate(assignment.Subject)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role assignment removed successfully"})
+}
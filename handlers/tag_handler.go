@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/farellandr/fullstack2024-test/logging"
+	"github.com/farellandr/fullstack2024-test/models"
+	"github.com/farellandr/fullstack2024-test/problem"
+	"github.com/farellandr/fullstack2024-test/tenancy"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const maxTagLength = 50
+
+type ClientTagsRequest struct {
+	Tags []string `json:"tags" binding:"required,min=1"`
+}
+
+// AddClientTags godoc
+// @Summary Add tags to a client
+// @Description Attaches one or more tags to a client, creating unknown tags on the fly, and refreshes the cache.
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param slug path string true "The unique slug of the client to tag"
+// @Param tags body ClientTagsRequest true "Tag names to attach"
+// @Success 200 {object} models.Client "The client with its updated tags"
+// @Failure 400 {object} problem.Problem "Invalid tag names"
+// @Failure 404 {object} problem.Problem "Client not found"
+// @Failure 500 {object} problem.Problem "Failed to tag client"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /clients/{slug}/tags [post]
+func (h *ClientHandler) AddClientTags(c *gin.Context) {
+	slug := c.Param("slug")
+	var client models.Client
+
+	if err := h.db(c).Where("slug = ?", slug).First(&client).Error; err != nil {
+		respondClientLookupError(c, err)
+		return
+	}
+
+	var req ClientTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Respond(c, problem.InvalidBody(err))
+		return
+	}
+
+	names, err := normalizeTags(req.Tags)
+	if err != nil {
+		problem.Respond(c, problem.BadRequest(err.Error()))
+		return
+	}
+
+	err = h.db(c).Transaction(func(tx *gorm.DB) error {
+		tags := make([]models.Tag, len(names))
+		for i, name := range names {
+			tags[i] = models.Tag{Name: name}
+		}
+
+		if err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "tenant_id"}, {Name: "name"}}, DoNothing: true}).Create(&tags).Error; err != nil {
+			return err
+		}
+
+		tags = nil
+		if err := tx.Where("name IN ?", names).Find(&tags).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&client).Association("Tags").Append(&tags)
+	})
+	if err != nil {
+		problem.Respond(c, problem.Internal("Failed to tag client", err))
+		return
+	}
+
+	h.refreshClientCache(c, &client)
+
+	c.JSON(http.StatusOK, client)
+}
+
+// RemoveClientTag godoc
+// @Summary Remove a tag from a client
+// @Description Detaches a tag from a client and refreshes the cache. The tag itself stays in the catalogue.
+// @Tags tags
+// @Produce json
+// @Param slug path string true "The unique slug of the client"
+// @Param tag path string true "The tag name to remove"
+// @Success 200 {object} models.Client "The client with its updated tags"
+// @Failure 404 {object} problem.Problem "Client or tag not found"
+// @Failure 500 {object} problem.Problem "Failed to untag client"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /clients/{slug}/tags/{tag} [delete]
+func (h *ClientHandler) RemoveClientTag(c *gin.Context) {
+	slug := c.Param("slug")
+	var client models.Client
+
+	if err := h.db(c).Where("slug = ?", slug).First(&client).Error; err != nil {
+		respondClientLookupError(c, err)
+		return
+	}
+
+	name, ok := normalizeTag(c.Param("tag"))
+	if !ok {
+		problem.Respond(c, problem.NotFound("Tag not found"))
+		return
+	}
+
+	var tag models.Tag
+	if err := h.db(c).Where("name = ?", name).First(&tag).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			problem.Respond(c, problem.NotFound("Tag not found"))
+		} else {
+			problem.Respond(c, problem.Internal("Failed to retrieve tag", err))
+		}
+		return
+	}
+
+	if err := h.db(c).Model(&client).Association("Tags").Delete(&tag); err != nil {
+		problem.Respond(c, problem.Internal("Failed to untag client", err))
+		return
+	}
+
+	h.refreshClientCache(c, &client)
+
+	c.JSON(http.StatusOK, client)
+}
+
+// GetTags godoc
+// @Summary List the tag catalogue
+// @Description Lists every known tag with the number of clients currently carrying it, most used first.
+// @Tags tags
+// @Produce json
+// @Success 200 {array} models.TagUsage "The tag catalogue"
+// @Failure 500 {object} problem.Problem "Failed to retrieve tags"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /tags [get]
+func (h *ClientHandler) GetTags(c *gin.Context) {
+	usages := []models.TagUsage{}
+
+	err := h.db(c).Model(&models.Tag{}).
+		Select("my_tag.name AS name, COUNT(my_client.id) AS count").
+		Joins("LEFT JOIN my_client_tag ON my_client_tag.tag_id = my_tag.id").
+		Joins("LEFT JOIN my_client ON my_client.id = my_client_tag.client_id AND my_client.deleted_at IS NULL").
+		Group("my_tag.id, my_tag.name").
+		Order("count DESC, my_tag.name").
+		Scan(&usages).Error
+	if err != nil {
+		problem.Respond(c, problem.Internal("Failed to retrieve tags", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, usages)
+}
+
+// refreshClientCache reloads the client with its tags and rewrites its cache entry.
+func (h *ClientHandler) refreshClientCache(c *gin.Context, client *models.Client) {
+	if err := h.db(c).Preload("Tags").First(client, client.ID).Error; err != nil {
+		logging.FromContext(c.Request.Context()).Warn("Failed to reload client for the cache", "error", err)
+		return
+	}
+
+	if err := h.Cache.Set(c.Request.Context(), tenancy.ID(c), client.Slug, client); err != nil {
+		logging.FromContext(c.Request.Context()).Warn("Failed to cache client", "error", err)
+	}
+}
+
+// clientsTaggedWith returns a subquery selecting the IDs of clients carrying
+// any of the given tags, or all of them when matchAll is set.
+func clientsTaggedWith(db *gorm.DB, names []string, matchAll bool) *gorm.DB {
+	query := db.Table("my_client_tag").
+		Select("my_client_tag.client_id").
+		Joins("JOIN my_tag ON my_tag.id = my_client_tag.tag_id").
+		Where("my_tag.name IN ?", names)
+
+	if matchAll {
+		query = query.Group("my_client_tag.client_id").Having("COUNT(DISTINCT my_tag.id) = ?", len(names))
+	}
+
+	return query
+}
+
+func normalizeTag(name string) (string, bool) {
+	tag := strings.ToLower(strings.TrimSpace(name))
+	tag = strings.Join(strings.Fields(tag), "-")
+
+	if tag == "" || len(tag) > maxTagLength {
+		return "", false
+	}
+
+	for _, r := range tag {
+		if !((r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' || r == '_') {
+			return "", false
+		}
+	}
+
+	return tag, true
+}
+
+// normalizeTags normalises and de-duplicates tag names, rejecting the whole
+// list if any name is invalid.
+func normalizeTags(names []string) ([]string, error) {
+	seen := make(map[string]bool, len(names))
+	tags := make([]string, 0, len(names))
+
+	for _, name := range names {
+		tag, ok := normalizeTag(name)
+		if !ok {
+			return nil, fmt.Errorf("invalid tag name %q: use letters, digits, '-' or '_', at most %d characters", name, maxTagLength)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags, nil
+}
+
+// splitTagParam parses a comma-separated tag list from a query parameter.
+func splitTagParam(value string) ([]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	return normalizeTags(strings.Split(value, ","))
+}
@@ -0,0 +1,176 @@
+// Package health answers the orchestrator's liveness and readiness probes.
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/farellandr/fullstack2024-test/problem"
+	"github.com/gin-gonic/gin"
+)
+
+// Check probes a single dependency and returns an error when it is
+// unreachable.
+type Check func(ctx context.Context) error
+
+type dependency struct {
+	name string
+	// critical dependencies fail readiness when they are down; the others
+	// are reported but have a fallback.
+	critical bool
+	check    Check
+}
+
+// Result is the outcome of one dependency check.
+type Result struct {
+	Status    string
+	Critical  bool
+	LatencyMs float64
+	Error     string `json:",omitempty"`
+}
+
+// Report is the body of both probe endpoints.
+type Report struct {
+	Status string
+	// Reason explains why the service is not ready, if it is not.
+	Reason string `json:",omitempty"`
+	Checks map[string]Result
+}
+
+// Probe states.
+const (
+	StatusUp          = "up"
+	StatusDown        = "down"
+	StatusOK          = "ok"
+	StatusDegraded    = "degraded"
+	StatusUnavailable = "unavailable"
+)
+
+// Checker runs the registered dependency checks and tracks whether the
+// service is ready for traffic. It starts out not ready.
+type Checker struct {
+	Timeout time.Duration
+
+	mu           sync.RWMutex
+	dependencies []dependency
+
+	ready    atomic.Bool
+	started  atomic.Bool
+	notReady atomic.Value
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	c := &Checker{Timeout: timeout}
+	c.notReady.Store("starting")
+	return c
+}
+
+// Add registers a dependency check.
+func (c *Checker) Add(name string, critical bool, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dependencies = append(c.dependencies, dependency{name: name, critical: critical, check: check})
+}
+
+// SetReady marks the service as ready for traffic.
+func (c *Checker) SetReady() {
+	c.started.Store(true)
+	c.ready.Store(true)
+}
+
+// SetNotReady fails readiness with reason, e.g. while migrating or draining.
+func (c *Checker) SetNotReady(reason string) {
+	c.notReady.Store(reason)
+	c.ready.Store(false)
+}
+
+// Run checks every dependency concurrently, each bounded by Timeout.
+func (c *Checker) Run(ctx context.Context) Report {
+	c.mu.RLock()
+	deps := c.dependencies
+	c.mu.RUnlock()
+
+	results := make([]Result, len(deps))
+	var wg sync.WaitGroup
+	for i, dep := range deps {
+		wg.Add(1)
+		go func(i int, dep dependency) {
+			defer wg.Done()
+			results[i] = c.run(ctx, dep)
+		}(i, dep)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(deps))}
+	for i, dep := range deps {
+		res := results[i]
+		report.Checks[dep.name] = res
+		if res.Status == StatusDown {
+			if dep.critical {
+				report.Status = StatusUnavailable
+			} else if report.Status == StatusOK {
+				report.Status = StatusDegraded
+			}
+		}
+	}
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, dep dependency) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := dep.check(ctx)
+	res := Result{
+		Status:    StatusUp,
+		Critical:  dep.critical,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		res.Status = StatusDown
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// Liveness reports the dependencies but always answers 200 while the process
+// serves requests: restarting it would not bring a dependency back.
+func (c *Checker) Liveness(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, c.Run(ctx.Request.Context()))
+}
+
+// Readiness answers 503 while the service is starting, migrating or
+// shutting down, or when a critical dependency is down.
+func (c *Checker) Readiness(ctx *gin.Context) {
+	if !c.ready.Load() {
+		ctx.JSON(http.StatusServiceUnavailable, Report{
+			Status: StatusUnavailable,
+			Reason: c.notReady.Load().(string),
+		})
+		return
+	}
+
+	report := c.Run(ctx.Request.Context())
+	if report.Status == StatusUnavailable {
+		ctx.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+	ctx.JSON(http.StatusOK, report)
+}
+
+// RequireStarted turns requests away with 503 until the service has first
+// become ready, so nothing reaches the API before migrations have run.
+// Requests arriving while draining are still served.
+func (c *Checker) RequireStarted() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !c.started.Load() {
+			problem.Respond(ctx, problem.Unavailable("Service is not ready"))
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,63 @@
+// Package logging builds the service's structured logger and carries a
+// request-scoped logger, tagged with the request ID, through the context.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/farellandr/fullstack2024-test/config"
+)
+
+// Redacted replaces the value of attributes holding contact details.
+const Redacted = "[REDACTED]"
+
+// redactedKeys are attribute keys, lowercased with separators removed, whose
+// values never reach the logs.
+var redactedKeys = map[string]bool{
+	"phonenumber": true,
+	"phone":       true,
+	"address":     true,
+}
+
+type ctxKey struct{}
+
+// New returns a logger writing to w in the configured format and level.
+func New(w io.Writer, cfg config.LoggingConfig) *slog.Logger {
+	opts := &slog.HandlerOptions{
+		Level:       cfg.Level,
+		ReplaceAttr: redact,
+	}
+
+	if cfg.Format == config.LogFormatText {
+		return slog.New(slog.NewTextHandler(w, opts))
+	}
+	return slog.New(slog.NewJSONHandler(w, opts))
+}
+
+// redact blanks attributes named like contact details, at any group depth.
+// Structs are opaque to it; models carrying such fields implement
+// slog.LogValuer instead.
+func redact(_ []string, a slog.Attr) slog.Attr {
+	key := strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(a.Key))
+	if redactedKeys[key] && a.Value.Kind() != slog.KindGroup {
+		return slog.String(a.Key, Redacted)
+	}
+	return a
+}
+
+// WithLogger returns a copy of ctx carrying l.
+func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext returns the request's logger, or the default logger outside a
+// request.
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
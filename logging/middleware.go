@@ -0,0 +1,96 @@
+package logging
+
+import (
+	"log/slog"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader carries the request ID in and out.
+const RequestIDHeader = "X-Request-ID"
+
+const maxRequestIDLength = 128
+
+// RequestID takes the caller's X-Request-ID, or generates one, echoes it in
+// the response and attaches a logger tagged with it (and the trace ID, when
+// the request is traced) to the request context.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		c.Header(RequestIDHeader, id)
+
+		ctx := c.Request.Context()
+		logger := slog.Default().With("request_id", id)
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			logger = logger.With("trace_id", sc.TraceID().String())
+		}
+		c.Request = c.Request.WithContext(WithLogger(ctx, logger))
+
+		c.Next()
+	}
+}
+
+// validRequestID accepts caller IDs that are safe to log and echo back.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r > unicode.MaxASCII || !unicode.IsPrint(r) || r == ' ' {
+			return false
+		}
+	}
+	return true
+}
+
+// AccessLog logs one line per request with its route, status, latency and
+// caller, as named by subject. Server errors log at error level, client
+// errors at warn, and probe and metrics scrapes at debug.
+func AccessLog(subject func(*gin.Context) string, quiet ...string) gin.HandlerFunc {
+	skip := make(map[string]bool, len(quiet))
+	for _, path := range quiet {
+		skip[path] = true
+	}
+
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		case skip[c.Request.URL.Path]:
+			level = slog.LevelDebug
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("route", c.FullPath()),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes", max(c.Writer.Size(), 0)),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if user := subject(c); user != "" {
+			attrs = append(attrs, slog.String("user", user))
+		}
+		if errs := c.Errors.ByType(gin.ErrorTypeAny); len(errs) > 0 {
+			attrs = append(attrs, slog.String("errors", errs.String()))
+		}
+
+		ctx := c.Request.Context()
+		FromContext(ctx).LogAttrs(ctx, level, "request", attrs...)
+	}
+}
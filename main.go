@@ -1,16 +1,33 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/farellandr/fullstack2024-test/auth"
+	"github.com/farellandr/fullstack2024-test/cache"
 	"github.com/farellandr/fullstack2024-test/config"
 	_ "github.com/farellandr/fullstack2024-test/docs"
 	"github.com/farellandr/fullstack2024-test/handlers"
+	"github.com/farellandr/fullstack2024-test/health"
+	"github.com/farellandr/fullstack2024-test/logging"
+	"github.com/farellandr/fullstack2024-test/metrics"
+	"github.com/farellandr/fullstack2024-test/middleware"
 	"github.com/farellandr/fullstack2024-test/models"
+	"github.com/farellandr/fullstack2024-test/problem"
+	"github.com/farellandr/fullstack2024-test/tenancy"
+	"github.com/farellandr/fullstack2024-test/tracing"
 	"github.com/farellandr/fullstack2024-test/utils"
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 
 	"github.com/joho/godotenv"
 )
@@ -20,35 +37,300 @@ import (
 // @description ASI Asia Pacific Fullstack test.
 // @host localhost:3222
 // @BasePath /api/v1
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Bearer token: "Bearer <JWT>"
+// @securityDefinitions.apikey APIKeyAuth
+// @in header
+// @name X-API-Key
 func main() {
-	if err := godotenv.Load(); err != nil {
-		log.Println(".env file not found")
+	envErr := godotenv.Load()
+
+	loggingConfig, err := config.LoadLoggingConfig()
+	if err != nil {
+		fatal("Invalid logging configuration", err)
+	}
+	slog.SetDefault(logging.New(os.Stdout, loggingConfig))
+
+	if envErr != nil {
+		slog.Info(".env file not found")
+	}
+
+	authConfig, err := config.LoadAuthConfig()
+	if err != nil {
+		fatal("Invalid auth configuration", err)
+	}
+
+	verifier, err := auth.NewJWTVerifier(authConfig)
+	if err != nil {
+		fatal("Failed to initialise JWT verification", err)
+	}
+
+	rateLimitConfig, err := config.LoadRateLimitConfig()
+	if err != nil {
+		fatal("Invalid rate limit configuration", err)
+	}
+
+	serverConfig, err := config.LoadServerConfig()
+	if err != nil {
+		fatal("Invalid server configuration", err)
+	}
+
+	cacheConfig, err := config.LoadCacheConfig()
+	if err != nil {
+		fatal("Invalid cache configuration", err)
+	}
+
+	redisConfig, err := config.LoadRedisConfig()
+	if err != nil {
+		fatal("Invalid Redis configuration", err)
+	}
+
+	timeoutConfig, err := config.LoadTimeoutConfig()
+	if err != nil {
+		fatal("Invalid request timeout configuration", err)
+	}
+
+	tracingConfig, err := config.LoadTracingConfig()
+	if err != nil {
+		fatal("Invalid tracing configuration", err)
+	}
+
+	shutdownTracing, err := tracing.Init(context.Background(), tracingConfig)
+	if err != nil {
+		fatal("Failed to initialise tracing", err)
 	}
 
 	db, err := config.InitDB()
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		fatal("Failed to connect to database", err)
 	}
 
-	db.AutoMigrate(&models.Client{})
+	if err := metrics.RegisterGorm(db); err != nil {
+		fatal("Failed to register query metrics", err)
+	}
+
+	if err := tracing.RegisterGorm(db); err != nil {
+		fatal("Failed to register query tracing", err)
+	}
 
-	redisClient := utils.InitRedis()
+	redisClient := utils.InitRedis(redisConfig)
+	clientCache, err := cache.New(cacheConfig, redisClient.Client)
+	if err != nil {
+		fatal("Failed to initialise cache", err)
+	}
 	s3Service := utils.InitS3()
+	roles := auth.NewRoleStore(db)
+	apiKeys := auth.NewAPIKeyStore(db)
+	rateLimiter := middleware.NewRateLimiter(rateLimitConfig, redisClient)
+
+	checker := health.NewChecker(serverConfig.HealthCheckTimeout)
+	checker.Add("postgres", true, func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	})
+	// The cache and rate limiter fall back without Redis, and only logo
+	// uploads need S3, so neither takes the service out of rotation.
+	checker.Add("redis", false, redisClient.Ping)
+	checker.Add("redis_circuit_breaker", false, redisClient.CheckBreaker)
+	checker.Add("s3", false, func(ctx context.Context) error {
+		if s3Service == nil {
+			return errors.New("S3 is not configured")
+		}
+		return s3Service.HeadBucket(ctx)
+	})
+
+	router := gin.New()
+	if err := router.SetTrustedProxies(serverConfig.TrustedProxies); err != nil {
+		fatal("Invalid TRUSTED_PROXIES", err)
+	}
+	router.Use(
+		otelgin.Middleware(tracingConfig.ServiceName, otelgin.WithFilter(func(r *http.Request) bool {
+			switch r.URL.Path {
+			case "/healthz", "/readyz", "/metrics":
+				return false
+			}
+			return true
+		})),
+		logging.RequestID(),
+		logging.AccessLog(auth.Subject, "/healthz", "/readyz", "/metrics"),
+		problem.Recovery(),
+		metrics.Middleware(),
+		middleware.MaxBodySize(serverConfig.MaxBodyBytes),
+	)
+
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(problem.NotFoundHandler)
+	router.NoMethod(problem.MethodNotAllowedHandler)
 
-	router := gin.Default()
+	router.GET("/healthz", checker.Liveness)
+	router.GET("/readyz", checker.Readiness)
+	router.GET("/metrics", metrics.Handler())
 
-	clientHandler := handlers.NewClientHandler(db, redisClient, s3Service)
-	api := router.Group("/api/v1")
+	clients := cache.NewClients(clientCache, cacheConfig)
+	clientHandler := handlers.NewClientHandler(db, clients, s3Service)
+	warmer := cache.NewWarmer(db, clients)
+	consistency := cache.NewConsistencyChecker(db, clients)
+	cacheHandler := handlers.NewCacheHandler(db, clients, warmer, consistency)
+	apiKeyHandler := handlers.NewAPIKeyHandler(db)
+	roleHandler := handlers.NewRoleHandler(db, roles)
+
+	read := auth.Require(auth.PermClientsRead)
+	write := auth.Require(auth.PermClientsWrite)
+	remove := auth.Require(auth.PermClientsDelete)
+	logo := auth.Require(auth.PermClientsLogo)
+
+	api := router.Group("/api/v1",
+		checker.RequireStarted(),
+		middleware.Timeout(timeoutConfig),
+		rateLimiter.IPMiddleware(),
+		auth.Middleware(verifier, apiKeys, roles),
+		tenancy.Middleware(config.LoadTenancyConfig(), auth.TenantID, auth.CrossTenant),
+		rateLimiter.Middleware(),
+	)
 	{
-		api.POST("/clients", clientHandler.CreateClient)
-		api.GET("/clients", clientHandler.GetAllClients)
-		api.GET("/clients/:slug", clientHandler.GetClientBySlug)
-		api.PUT("/clients/:slug", clientHandler.UpdateClient)
-		api.DELETE("/clients/:slug", clientHandler.DeleteClient)
-		api.POST("/clients/:slug/logo", clientHandler.UploadClientLogo)
+		api.POST("/clients", write, clientHandler.CreateClient)
+		api.GET("/clients", read, clientHandler.GetAllClients)
+		api.GET("/clients/prefix-suggestions", read, clientHandler.GetPrefixSuggestions)
+		api.GET("/clients/:slug", read, clientHandler.GetClientBySlug)
+		api.PUT("/clients/:slug", write, clientHandler.UpdateClient)
+		api.DELETE("/clients/:slug", remove, clientHandler.DeleteClient)
+		api.POST("/clients/:slug/logo", logo, clientHandler.UploadClientLogo)
+		api.POST("/clients/:slug/tags", write, clientHandler.AddClientTags)
+		api.DELETE("/clients/:slug/tags/:tag", write, clientHandler.RemoveClientTag)
+		api.GET("/clients/:slug/children", read, clientHandler.GetClientChildren)
+		api.GET("/clients/:slug/ancestors", read, clientHandler.GetClientAncestors)
+		api.GET("/clients/:slug/tree", read, clientHandler.GetClientTree)
+		api.DELETE("/clients/:slug/parent", write, clientHandler.DetachClientParent)
+
+		api.GET("/tags", read, clientHandler.GetTags)
+
+		admin := api.Group("/admin")
+		{
+			keys := auth.Require(auth.PermAPIKeysAdmin)
+			admin.POST("/api-keys", keys, apiKeyHandler.CreateAPIKey)
+			admin.GET("/api-keys", keys, apiKeyHandler.GetAPIKeys)
+			admin.DELETE("/api-keys/:id", keys, apiKeyHandler.RevokeAPIKey)
+			admin.POST("/api-keys/:id/rotate", keys, apiKeyHandler.RotateAPIKey)
+
+			rbac := auth.Require(auth.PermRolesAdmin)
+			admin.GET("/roles", rbac, roleHandler.GetRoles)
+			admin.PUT("/roles/:name", rbac, roleHandler.PutRole)
+			admin.GET("/role-assignments", rbac, roleHandler.GetRoleAssignments)
+			admin.POST("/role-assignments", rbac, roleHandler.CreateRoleAssignment)
+			admin.DELETE("/role-assignments/:id", rbac, roleHandler.DeleteRoleAssignment)
+
+			cacheAdmin := auth.Require(auth.PermCacheAdmin)
+			admin.POST("/cache/consistency", cacheAdmin, cacheHandler.CheckConsistency)
+			admin.GET("/cache/consistency", cacheAdmin, cacheHandler.ConsistencyStatus)
+			admin.POST("/cache/warm-up", cacheAdmin, cacheHandler.StartWarmUp)
+			admin.GET("/cache/warm-up", cacheAdmin, cacheHandler.WarmUpProgress)
+		}
 	}
 
 	router.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	router.Run(":3222")
+	server := &http.Server{
+		Addr:              serverConfig.Addr(),
+		Handler:           router,
+		ReadHeaderTimeout: serverConfig.ReadHeaderTimeout,
+		ReadTimeout:       serverConfig.ReadTimeout,
+		WriteTimeout:      serverConfig.WriteTimeout,
+		IdleTimeout:       serverConfig.IdleTimeout,
+		MaxHeaderBytes:    serverConfig.MaxHeaderBytes,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("Listening", "addr", server.Addr)
+		serveErr <- server.ListenAndServe()
+	}()
+
+	// Migrations run with the listener up so probes can see them; the API
+	// stays closed until they are done.
+	checker.SetNotReady("migrating")
+	if err := models.Migrate(db); err != nil {
+		fatal("Failed to migrate database", err)
+	}
+
+	if err := roles.Seed(authConfig.AdminSubjects); err != nil {
+		fatal("Failed to seed roles", err)
+	}
+
+	if err := tenancy.Register(db); err != nil {
+		fatal("Failed to register tenant scoping", err)
+	}
+	checker.SetReady()
+
+	// Preload the cache so traffic after a Redis flush or failover does not
+	// all fall through to the database; requests are served meanwhile.
+	if cacheConfig.WarmUpCount > 0 {
+		_, err := warmer.Start(ctx, cache.WarmUpOptions{
+			Strategy:    cache.WarmRecent,
+			Count:       cacheConfig.WarmUpCount,
+			Concurrency: cacheConfig.WarmUpConcurrency,
+			BatchSize:   cacheConfig.WarmUpBatchSize,
+		})
+		if err != nil {
+			slog.Warn("Failed to start cache warm-up", "error", err)
+		}
+	}
+
+	select {
+	case err := <-serveErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			fatal("Server failed", err)
+		}
+	case <-ctx.Done():
+		stop()
+
+		// Fail readiness first and keep serving for a while, so the load
+		// balancer stops routing here before the listener closes.
+		checker.SetNotReady("shutting down")
+		time.Sleep(serverConfig.ShutdownDelay)
+
+		slog.Info("Shutting down, draining requests", "timeout", serverConfig.ShutdownTimeout)
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), serverConfig.ShutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			slog.Warn("Requests still in flight at shutdown deadline", "error", err)
+			server.Close()
+		}
+	}
+
+	// Dependencies close only once no handler can still be using them.
+	warmer.Stop()
+	consistency.Stop()
+	if sqlDB, err := db.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			slog.Warn("Failed to close database pool", "error", err)
+		}
+	}
+	if err := clientCache.Close(); err != nil {
+		slog.Warn("Failed to close cache", "error", err)
+	}
+	if err := redisClient.Close(); err != nil {
+		slog.Warn("Failed to close Redis client", "error", err)
+	}
+
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		slog.Warn("Failed to flush traces", "error", err)
+	}
+	slog.Info("Shutdown complete")
+}
+
+// fatal logs err and exits; startup cannot continue without the dependency.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
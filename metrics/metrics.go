@@ -0,0 +1,122 @@
+// Package metrics defines the Prometheus metrics exposed on /metrics. They are
+// registered with the default registry, which also carries the Go runtime and
+// process collectors.
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Cache results counted by CacheResult.
+const (
+	CacheHit   = "hit"
+	CacheMiss  = "miss"
+	CacheError = "error"
+	// CacheStale is a hit on an expired entry, served while it is refreshed.
+	CacheStale = "stale"
+	// CacheNegativeHit is a hit on an entry recording that a key does not
+	// exist.
+	CacheNegativeHit = "negative_hit"
+)
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by method, route and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	dbDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Database statement latency by operation and table.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table", "result"})
+
+	cacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cache_requests_total",
+		Help: "Cache lookups by cache and result (hit, miss, error).",
+	}, []string{"cache", "result"})
+
+	breakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "circuit_breaker_state",
+		Help: "Circuit breaker state by dependency: 0 closed, 1 half-open, 2 open.",
+	}, []string{"dependency"})
+
+	breakerTransitions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "circuit_breaker_transitions_total",
+		Help: "Circuit breaker state changes by dependency and new state.",
+	}, []string{"dependency", "state"})
+
+	s3UploadBytes = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "s3_upload_size_bytes",
+		Help:    "Size of files uploaded to S3.",
+		Buckets: prometheus.ExponentialBuckets(1<<10, 4, 8),
+	})
+
+	s3UploadDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "s3_upload_duration_seconds",
+		Help:    "S3 upload latency by result.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"result"})
+)
+
+// Handler serves the metrics in the Prometheus exposition format.
+func Handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.Handler())
+}
+
+// Middleware records the count and latency of every request under its route
+// template, so that slugs do not explode the label set.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+
+		httpRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		httpDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// CacheResult counts a lookup in the named cache.
+func CacheResult(cache, result string) {
+	cacheRequests.WithLabelValues(cache, result).Inc()
+}
+
+// SetBreakerState records the current state of a dependency's circuit
+// breaker.
+func SetBreakerState(dependency string, state int) {
+	breakerState.WithLabelValues(dependency).Set(float64(state))
+}
+
+// BreakerTransition counts a circuit breaker moving to state.
+func BreakerTransition(dependency, state string) {
+	breakerTransitions.WithLabelValues(dependency, state).Inc()
+}
+
+// ObserveS3Upload records an upload of size bytes that took d.
+func ObserveS3Upload(size int64, d time.Duration, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	} else {
+		s3UploadBytes.Observe(float64(size))
+	}
+	s3UploadDuration.WithLabelValues(result).Observe(d.Seconds())
+}
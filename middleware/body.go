@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/farellandr/fullstack2024-test/problem"
+	"github.com/gin-gonic/gin"
+)
+
+// MaxBodySize rejects requests whose body exceeds limit bytes: up front with
+// 413 when Content-Length gives it away, otherwise by failing the read.
+func MaxBodySize(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > limit {
+			problem.Respond(c, problem.PayloadTooLarge(fmt.Sprintf("Request body exceeds %d bytes", limit)))
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
@@ -0,0 +1,197 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/farellandr/fullstack2024-test/auth"
+	"github.com/farellandr/fullstack2024-test/config"
+	"github.com/farellandr/fullstack2024-test/problem"
+	"github.com/farellandr/fullstack2024-test/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimiter throttles requests per caller and route with token buckets
+// stored in Redis, so every replica shares the same budget. While Redis is
+// unreachable each replica falls back to its own in-memory buckets.
+type RateLimiter struct {
+	Config config.RateLimitConfig
+	Redis  *utils.RedisClient
+
+	local    *localBuckets
+	degraded atomic.Bool
+}
+
+func NewRateLimiter(cfg config.RateLimitConfig, redisClient *utils.RedisClient) *RateLimiter {
+	return &RateLimiter{
+		Config: cfg,
+		Redis:  redisClient,
+		local:  newLocalBuckets(),
+	}
+}
+
+// IPMiddleware limits every request from one client IP, across routes, to
+// the PerIP budget. It runs before authentication, so requests with wrong
+// credentials count too.
+func (l *RateLimiter) IPMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !l.Config.Enabled || l.Config.PerIP.Disabled() {
+			c.Next()
+			return
+		}
+
+		l.limit(c, "ip:"+c.ClientIP(), l.Config.PerIP)
+	}
+}
+
+// Middleware limits requests per caller and route. It must run after
+// authentication so that callers are told apart by API key or user rather
+// than by IP address.
+func (l *RateLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !l.Config.Enabled {
+			c.Next()
+			return
+		}
+
+		route := c.Request.Method + " " + c.FullPath()
+		limit := l.limitFor(route)
+		if limit.Disabled() {
+			c.Next()
+			return
+		}
+
+		l.limit(c, route+":"+callerKey(c), limit)
+	}
+}
+
+// limit takes a token from the bucket for key and either rejects the request
+// or passes it on, reporting the budget left in RateLimit headers.
+func (l *RateLimiter) limit(c *gin.Context, key string, limit config.RateLimit) {
+	allowed, remaining := l.take(c.Request.Context(), key, limit)
+
+	perSecond := float64(limit.Requests) / limit.Window.Seconds()
+	h := c.Writer.Header()
+	h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, int(math.Ceil(limit.Window.Seconds()))))
+	h.Set("RateLimit-Limit", strconv.Itoa(limit.Requests))
+	h.Set("RateLimit-Remaining", strconv.Itoa(int(remaining)))
+	h.Set("RateLimit-Reset", strconv.Itoa(secondsUntil(float64(limit.Requests)-remaining, perSecond)))
+
+	if !allowed {
+		h.Set("Retry-After", strconv.Itoa(secondsUntil(1-remaining, perSecond)))
+		problem.Respond(c, problem.RateLimited("Rate limit exceeded"))
+		return
+	}
+
+	c.Next()
+}
+
+func (l *RateLimiter) limitFor(route string) config.RateLimit {
+	if limit, ok := l.Config.Routes[route]; ok {
+		return limit
+	}
+	return l.Config.Default
+}
+
+func (l *RateLimiter) take(ctx context.Context, key string, limit config.RateLimit) (bool, float64) {
+	if l.Redis != nil {
+		allowed, remaining, err := l.Redis.TakeToken(ctx, key, limit.Requests, limit.Window)
+		if err == nil {
+			if l.degraded.Swap(false) {
+				slog.Info("Rate limiting is back on Redis")
+			}
+			return allowed, remaining
+		}
+
+		if !l.degraded.Swap(true) {
+			slog.Warn("Rate limiting falls back to memory, Redis failed", "error", err)
+		}
+	}
+
+	return l.local.take(key, limit, time.Now())
+}
+
+// callerKey identifies who a request counts against: the API key, else the
+// authenticated user. The client IP is only used for routes mounted without
+// authentication.
+func callerKey(c *gin.Context) string {
+	if p := auth.CurrentPrincipal(c); p != nil {
+		if p.Method == auth.MethodAPIKey {
+			return "key:" + strconv.FormatUint(uint64(p.APIKeyID), 10)
+		}
+		if p.Subject != "" {
+			return "user:" + p.Subject
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func secondsUntil(tokens, perSecond float64) int {
+	if tokens <= 0 {
+		return 0
+	}
+	return int(math.Ceil(tokens / perSecond))
+}
+
+type localBucket struct {
+	tokens float64
+	last   time.Time
+	window time.Duration
+}
+
+// localBuckets mirrors the Redis token bucket within a single process.
+type localBuckets struct {
+	mu        sync.Mutex
+	buckets   map[string]*localBucket
+	lastSweep time.Time
+}
+
+func newLocalBuckets() *localBuckets {
+	return &localBuckets{buckets: make(map[string]*localBucket)}
+}
+
+func (b *localBuckets) take(key string, limit config.RateLimit, now time.Time) (bool, float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	capacity := float64(limit.Requests)
+	perSecond := capacity / limit.Window.Seconds()
+
+	bucket, ok := b.buckets[key]
+	if !ok {
+		bucket = &localBucket{tokens: capacity, last: now, window: limit.Window}
+		b.buckets[key] = bucket
+	}
+
+	bucket.tokens = math.Min(capacity, bucket.tokens+now.Sub(bucket.last).Seconds()*perSecond)
+	bucket.last = now
+
+	allowed := bucket.tokens >= 1
+	if allowed {
+		bucket.tokens--
+	}
+
+	b.sweep(now)
+	return allowed, bucket.tokens
+}
+
+// sweep drops buckets idle for a whole window: they have refilled to full and
+// are equivalent to missing ones.
+func (b *localBuckets) sweep(now time.Time) {
+	if now.Sub(b.lastSweep) < time.Minute {
+		return
+	}
+	b.lastSweep = now
+
+	for key, bucket := range b.buckets {
+		if now.Sub(bucket.last) >= bucket.window {
+			delete(b.buckets, key)
+		}
+	}
+}
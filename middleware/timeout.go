@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+
+	"github.com/farellandr/fullstack2024-test/config"
+	"github.com/farellandr/fullstack2024-test/problem"
+	"github.com/gin-gonic/gin"
+)
+
+// Timeout puts a deadline on the request context, so database, Redis and S3
+// calls made with it give up once the route's budget is spent. Handlers
+// report the expiry themselves; if one wrote nothing, a 504 is sent here.
+func Timeout(cfg config.TimeoutConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		d := cfg.For(c.Request.Method + " " + c.FullPath())
+		if d <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && !c.Writer.Written() {
+			problem.Respond(c, problem.Timeout("Request timed out"))
+		}
+	}
+}
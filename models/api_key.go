@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+)
+
+// APIKey is a credential for machine-to-machine integrations. Only a hash of
+// the secret is stored; the full key is shown once when it is minted.
+type APIKey struct {
+	ID            uint       `gorm:"primaryKey"`
+	TenantID      string     `gorm:"size:64;not null;default:'default';index"`
+	Name          string     `gorm:"size:100;not null"`
+	KeyID         string     `gorm:"size:16;not null;uniqueIndex"`
+	SecretHash    string     `gorm:"size:64;not null" json:"-"`
+	Scopes        []string   `gorm:"serializer:json;type:text;not null"`
+	ExpiresAt     *time.Time `gorm:"default:null"`
+	LastUsedAt    *time.Time `gorm:"default:null"`
+	RevokedAt     *time.Time `gorm:"default:null"`
+	RotatedFromID *uint      `gorm:"default:null"`
+	CreatedBy     string     `gorm:"size:255"`
+	CreatedAt     time.Time  `gorm:"default:null"`
+	UpdatedAt     time.Time  `gorm:"default:null"`
+}
+
+func (APIKey) TableName() string {
+	return "my_api_key"
+}
+
+// Active reports whether the key may still authenticate at time now.
+func (k *APIKey) Active(now time.Time) bool {
+	if k.RevokedAt != nil && !k.RevokedAt.After(now) {
+		return false
+	}
+	if k.ExpiresAt != nil && !k.ExpiresAt.After(now) {
+		return false
+	}
+	return true
+}
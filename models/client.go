@@ -1,6 +1,8 @@
 package models
 
 import (
+	"log/slog"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -8,20 +10,69 @@ import (
 
 type Client struct {
 	ID           uint      `gorm:"primaryKey"`
+	TenantID     string    `gorm:"size:64;not null;default:'default';index;uniqueIndex:idx_my_client_tenant_prefix,priority:1,where:deleted_at IS NULL"`
 	Name         string    `gorm:"size:250;not null"`
 	Slug         string    `gorm:"size:100;not null;"`
 	IsProject    string    `gorm:"size:30;check:(is_project in ('0','1'));not null;default:'0'"`
 	SelfCapture  string    `gorm:"size:1;not null;default:'1'"`
-	ClientPrefix string    `gorm:"size:4;not null"`
+	ClientPrefix string    `gorm:"size:4;not null;uniqueIndex:idx_my_client_tenant_prefix,priority:2,where:deleted_at IS NULL"`
 	ClientLogo   string    `gorm:"size:255;not null;default:'no-image.jpg'"`
 	Address      string    `gorm:"type:text"`
 	PhoneNumber  string    `gorm:"size:50"`
-	City         string    `gorm:"size:50"`
+	City         string    `gorm:"size:50;index:idx_my_client_region,priority:3"`
+	Street       string    `gorm:"size:255"`
+	District     string    `gorm:"size:100"`
+	Province     string    `gorm:"size:100;index:idx_my_client_region,priority:2"`
+	PostalCode   string    `gorm:"size:20"`
+	CountryCode  string    `gorm:"size:2;index:idx_my_client_region,priority:1"`
 	CreatedAt    time.Time `gorm:"default:null"`
 	UpdatedAt    time.Time `gorm:"default:null"`
 	DeletedAt    gorm.DeletedAt
+	ParentID     *uint   `gorm:"index"`
+	Parent       *Client `gorm:"foreignKey:ParentID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"-" swaggerignore:"true"`
+	Tags         []Tag   `gorm:"many2many:my_client_tag;"`
 }
 
 func (Client) TableName() string {
 	return "my_client"
 }
+
+// HasStructuredAddress reports whether any structured address component is set.
+func (c *Client) HasStructuredAddress() bool {
+	return c.Street != "" || c.District != "" || c.City != "" || c.Province != "" || c.PostalCode != ""
+}
+
+// FormatAddress renders the structured address components as a single line,
+// the form kept in the legacy Address column.
+func (c *Client) FormatAddress() string {
+	var parts []string
+	for _, part := range []string{c.Street, c.District, c.City} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	if region := strings.TrimSpace(c.Province + " " + c.PostalCode); region != "" {
+		parts = append(parts, region)
+	}
+
+	if c.CountryCode != "" {
+		parts = append(parts, c.CountryCode)
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// LogValue keeps contact details out of logs: clients log as their identity
+// only, with the phone number and address redacted.
+func (c Client) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Uint64("ID", uint64(c.ID)),
+		slog.String("TenantID", c.TenantID),
+		slog.String("Slug", c.Slug),
+		slog.String("Name", c.Name),
+		slog.String("ClientPrefix", c.ClientPrefix),
+		slog.String("PhoneNumber", "[REDACTED]"),
+		slog.String("Address", "[REDACTED]"),
+	)
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// clientPrefixIndex makes the prefixes of live clients unique per tenant.
+const clientPrefixIndex = "idx_my_client_tenant_prefix"
+
+// legacyIndexes were replaced by tenant-aware indexes and must not survive
+// AutoMigrate, which never drops indexes on its own.
+var legacyIndexes = []struct {
+	model interface{}
+	name  string
+}{
+	{&Client{}, "idx_my_client_client_prefix"},
+	{&Tag{}, "idx_my_tag_name"},
+	{&RoleAssignment{}, "idx_my_role_assignment_subject_role"},
+}
+
+// Migrate brings the schema up to date. It runs before tenant scoping is
+// registered on db.
+func Migrate(db *gorm.DB) error {
+	if err := checkClientPrefixes(db); err != nil {
+		return err
+	}
+
+	if err := db.AutoMigrate(&Client{}, &Tag{}, &APIKey{}, &Role{}, &RoleAssignment{}); err != nil {
+		return err
+	}
+
+	for _, idx := range legacyIndexes {
+		if db.Migrator().HasIndex(idx.model, idx.name) {
+			if err := db.Migrator().DropIndex(idx.model, idx.name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkClientPrefixes fails, naming the clients involved, when live clients
+// of a tenant share a prefix: the unique prefix index could not be created.
+// Prefixes may be printed on documents, so they are not renamed here.
+func checkClientPrefixes(db *gorm.DB) error {
+	m := db.Migrator()
+	if !m.HasTable(&Client{}) || m.HasIndex(&Client{}, clientPrefixIndex) {
+		return nil
+	}
+
+	// Databases from before tenancy have no tenant column; every client
+	// will belong to the default tenant.
+	columns := "client_prefix"
+	if m.HasColumn(&Client{}, "TenantID") {
+		columns = "tenant_id, client_prefix"
+	}
+
+	var groups []struct {
+		Tenant string `gorm:"column:tenant_id"`
+		Prefix string `gorm:"column:client_prefix"`
+	}
+	err := db.Table(Client{}.TableName()).Select(columns).
+		Where("deleted_at IS NULL").
+		Group(columns).Having("COUNT(*) > 1").
+		Scan(&groups).Error
+	if err != nil || len(groups) == 0 {
+		return err
+	}
+
+	conflicts := make([]string, 0, len(groups))
+	for _, g := range groups {
+		query := db.Table(Client{}.TableName()).Select("id, slug").
+			Where("deleted_at IS NULL AND client_prefix = ?", g.Prefix)
+		if g.Tenant != "" {
+			query = query.Where("tenant_id = ?", g.Tenant)
+		}
+
+		var clients []struct {
+			ID   uint
+			Slug string
+		}
+		if err := query.Order("id").Scan(&clients).Error; err != nil {
+			return err
+		}
+
+		ids := make([]string, len(clients))
+		for i, c := range clients {
+			ids[i] = fmt.Sprintf("%d (%s)", c.ID, c.Slug)
+		}
+		conflict := fmt.Sprintf("prefix %q: clients %s", g.Prefix, strings.Join(ids, ", "))
+		if g.Tenant != "" {
+			conflict = fmt.Sprintf("tenant %q, %s", g.Tenant, conflict)
+		}
+		conflicts = append(conflicts, conflict)
+	}
+
+	return fmt.Errorf("live clients share a client prefix, so %s cannot be created; "+
+		"give each a distinct prefix or delete the duplicates, then restart:\n%s",
+		clientPrefixIndex, strings.Join(conflicts, "\n"))
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+)
+
+// Role is a named set of permissions that can be assigned to users.
+type Role struct {
+	ID          uint      `gorm:"primaryKey"`
+	Name        string    `gorm:"size:50;not null;uniqueIndex"`
+	Description string    `gorm:"size:255"`
+	Permissions []string  `gorm:"serializer:json;type:text;not null"`
+	CreatedAt   time.Time `gorm:"default:null"`
+	UpdatedAt   time.Time `gorm:"default:null"`
+}
+
+func (Role) TableName() string {
+	return "my_role"
+}
+
+// AllTenants is the TenantID of role assignments held in every tenant. Only
+// such assignments apply to tokens not bound to a tenant.
+const AllTenants = "*"
+
+// RoleAssignment grants a role to a token subject within one tenant, or
+// within all of them.
+type RoleAssignment struct {
+	ID        uint      `gorm:"primaryKey"`
+	TenantID  string    `gorm:"size:64;not null;default:'default';uniqueIndex:idx_my_role_assignment_subject_tenant_role,priority:2"`
+	Subject   string    `gorm:"size:255;not null;uniqueIndex:idx_my_role_assignment_subject_tenant_role,priority:1"`
+	RoleID    uint      `gorm:"not null;uniqueIndex:idx_my_role_assignment_subject_tenant_role,priority:3"`
+	Role      Role      `gorm:"constraint:OnDelete:CASCADE;"`
+	CreatedBy string    `gorm:"size:255"`
+	CreatedAt time.Time `gorm:"default:null"`
+}
+
+func (RoleAssignment) TableName() string {
+	return "my_role_assignment"
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+type Tag struct {
+	ID        uint      `gorm:"primaryKey"`
+	TenantID  string    `gorm:"size:64;not null;default:'default';uniqueIndex:idx_my_tag_tenant_name"`
+	Name      string    `gorm:"size:50;not null;uniqueIndex:idx_my_tag_tenant_name"`
+	CreatedAt time.Time `gorm:"default:null"`
+	UpdatedAt time.Time `gorm:"default:null"`
+}
+
+func (Tag) TableName() string {
+	return "my_tag"
+}
+
+// TagUsage is a catalogue entry: a tag with the number of live clients carrying it.
+type TagUsage struct {
+	Name  string
+	Count int64
+}
@@ -0,0 +1,145 @@
+// Package problem reports errors to clients as RFC 7807 problem details
+// (application/problem+json). Domain code returns *Error values of a Kind;
+// Respond turns any error into a problem, hiding the cause of unexpected
+// ones.
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ContentType is the media type of problem responses.
+const ContentType = "application/problem+json"
+
+// TypeBase prefixes the type URI of every problem. Type URIs are part of the
+// API: clients may switch on them, so they never change once published.
+const TypeBase = "urn:fullstack2024:problem:"
+
+// Kind is a class of problem with a stable type URI, title and status.
+type Kind struct {
+	Slug   string
+	Title  string
+	Status int
+}
+
+// Type returns the kind's type URI.
+func (k Kind) Type() string {
+	return TypeBase + k.Slug
+}
+
+// Problem kinds returned by the API.
+var (
+	KindBadRequest          = Kind{"bad-request", "Malformed request", http.StatusBadRequest}
+	KindUnauthorized        = Kind{"unauthorized", "Authentication required", http.StatusUnauthorized}
+	KindForbidden           = Kind{"forbidden", "Not permitted", http.StatusForbidden}
+	KindNotFound            = Kind{"not-found", "Resource not found", http.StatusNotFound}
+	KindMethodNotAllowed    = Kind{"method-not-allowed", "Method not allowed", http.StatusMethodNotAllowed}
+	KindConflict            = Kind{"conflict", "Conflicts with the current state", http.StatusConflict}
+	KindPreconditionFailed  = Kind{"precondition-failed", "Precondition failed", http.StatusPreconditionFailed}
+	KindPayloadTooLarge     = Kind{"payload-too-large", "Request body too large", http.StatusRequestEntityTooLarge}
+	KindValidation          = Kind{"validation-failed", "Validation failed", http.StatusUnprocessableEntity}
+	KindRateLimited         = Kind{"rate-limited", "Too many requests", http.StatusTooManyRequests}
+	KindInternal            = Kind{"internal", "Internal server error", http.StatusInternalServerError}
+	KindUnavailable         = Kind{"unavailable", "Service unavailable", http.StatusServiceUnavailable}
+	KindUpstreamUnavailable = Kind{"upstream-unavailable", "Upstream service unavailable", http.StatusServiceUnavailable}
+	KindTimeout             = Kind{"timeout", "Request timed out", http.StatusGatewayTimeout}
+)
+
+// Error is a domain error that knows how it is reported to clients. Err, the
+// cause, is logged but never shown to them.
+type Error struct {
+	Kind       Kind
+	Detail     string
+	Extensions map[string]interface{}
+	Err        error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Detail + ": " + e.Err.Error()
+	}
+	return e.Detail
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// With adds an extension member to the problem, e.g. the suggestions for a
+// misspelt city.
+func (e *Error) With(key string, value interface{}) *Error {
+	if e.Extensions == nil {
+		e.Extensions = make(map[string]interface{})
+	}
+	e.Extensions[key] = value
+	return e
+}
+
+// Wrap records cause as the reason behind e.
+func (e *Error) Wrap(cause error) *Error {
+	e.Err = cause
+	return e
+}
+
+// New returns a problem of kind with a human-readable detail.
+func New(kind Kind, detail string) *Error {
+	return &Error{Kind: kind, Detail: detail}
+}
+
+func BadRequest(detail string) *Error          { return New(KindBadRequest, detail) }
+func Unauthorized(detail string) *Error        { return New(KindUnauthorized, detail) }
+func Forbidden(detail string) *Error           { return New(KindForbidden, detail) }
+func NotFound(detail string) *Error            { return New(KindNotFound, detail) }
+func Conflict(detail string) *Error            { return New(KindConflict, detail) }
+func PreconditionFailed(detail string) *Error  { return New(KindPreconditionFailed, detail) }
+func PayloadTooLarge(detail string) *Error     { return New(KindPayloadTooLarge, detail) }
+func Validation(detail string) *Error          { return New(KindValidation, detail) }
+func RateLimited(detail string) *Error         { return New(KindRateLimited, detail) }
+func Unavailable(detail string) *Error         { return New(KindUnavailable, detail) }
+func UpstreamUnavailable(detail string) *Error { return New(KindUpstreamUnavailable, detail) }
+func Timeout(detail string) *Error             { return New(KindTimeout, detail) }
+
+// Internal reports an unexpected failure. detail says what failed, not why;
+// the cause goes to the logs only.
+func Internal(detail string, cause error) *Error {
+	return New(KindInternal, detail).Wrap(cause)
+}
+
+// Problem is the wire format of RFC 7807.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Extensions are serialised as top-level members.
+	Extensions map[string]interface{} `json:"-" swaggerignore:"true"`
+}
+
+// MarshalJSON writes the extension members next to the standard ones, which
+// they cannot override.
+func (p Problem) MarshalJSON() ([]byte, error) {
+	type standard Problem
+	body, err := json.Marshal(standard(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return body, err
+	}
+
+	members := make(map[string]interface{}, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		members[k] = v
+	}
+	if err := json.Unmarshal(body, &members); err != nil {
+		return nil, err
+	}
+	return json.Marshal(members)
+}
+
+// As returns err as an *Error, or false if it is not one.
+func As(err error) (*Error, bool) {
+	var e *Error
+	ok := errors.As(err, &e)
+	return e, ok
+}
@@ -0,0 +1,133 @@
+package problem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/farellandr/fullstack2024-test/logging"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// Respond writes err as a problem and aborts the request. Errors that are not
+// an *Error are reported as internal errors without detail. Failures caused
+// by the request running out of time become timeouts.
+func Respond(c *gin.Context, err error) {
+	e, ok := As(err)
+	if !ok {
+		e = Internal("An unexpected error occurred", err)
+	}
+
+	if e.Kind == KindInternal || e.Kind == KindUpstreamUnavailable {
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(c.Request.Context().Err(), context.DeadlineExceeded) {
+			e = Timeout("The request did not complete in time").Wrap(err)
+		}
+	}
+
+	ctx := c.Request.Context()
+	switch {
+	case e.Kind.Status >= 500 && e.Err != nil:
+		logging.FromContext(ctx).ErrorContext(ctx, e.Detail, "problem", e.Kind.Slug, "error", e.Err)
+	case errors.Is(ctx.Err(), context.Canceled):
+		logging.FromContext(ctx).WarnContext(ctx, e.Detail+": client went away", "error", e.Err)
+	}
+
+	write(c, e)
+}
+
+func write(c *gin.Context, e *Error) {
+	p := Problem{
+		Type:       e.Kind.Type(),
+		Title:      e.Kind.Title,
+		Status:     e.Kind.Status,
+		Detail:     e.Detail,
+		Instance:   c.Request.URL.Path,
+		Extensions: e.Extensions,
+	}
+	if id := c.Writer.Header().Get(logging.RequestIDHeader); id != "" {
+		// e may be shared between requests, so its extensions are copied
+		// rather than given this request's ID.
+		p.Extensions = make(map[string]interface{}, len(e.Extensions)+1)
+		maps.Copy(p.Extensions, e.Extensions)
+		p.Extensions["request_id"] = id
+	}
+
+	body, err := json.Marshal(p)
+	if err != nil {
+		body = []byte(`{"type":"` + KindInternal.Type() + `","title":"` + KindInternal.Title + `","status":500}`)
+		p.Status = http.StatusInternalServerError
+	}
+
+	c.Abort()
+	c.Data(p.Status, ContentType, body)
+}
+
+// FieldError names a request field that failed validation.
+type FieldError struct {
+	Field string `json:"field"`
+	Rule  string `json:"rule"`
+}
+
+// InvalidBody reports a request body that could not be bound, listing the
+// failed fields when the body parsed but did not validate.
+func InvalidBody(err error) *Error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return PayloadTooLarge(fmt.Sprintf("Request body exceeds %d bytes", tooLarge.Limit)).Wrap(err)
+	}
+
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return BadRequest("Request body is not valid JSON for this endpoint").Wrap(err)
+	}
+
+	fields := make([]FieldError, len(verrs))
+	for i, fe := range verrs {
+		fields[i] = FieldError{Field: fe.Field(), Rule: fe.Tag()}
+	}
+	return BadRequest("Request body failed validation").With("errors", fields).Wrap(err)
+}
+
+// Recovery turns panics into 500 problems. The panic value and stack are
+// logged; the client learns nothing about them.
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			ctx := c.Request.Context()
+			logging.FromContext(ctx).ErrorContext(ctx, "Panic while handling request",
+				"panic", rec, "stack", string(debug.Stack()))
+
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			write(c, New(KindInternal, "An unexpected error occurred"))
+		}()
+
+		c.Next()
+	}
+}
+
+// NotFoundHandler answers requests that match no route.
+func NotFoundHandler(c *gin.Context) {
+	write(c, NotFound("No such endpoint"))
+}
+
+// MethodNotAllowedHandler answers requests for a route that does not accept
+// their method.
+func MethodNotAllowedHandler(c *gin.Context) {
+	write(c, New(KindMethodNotAllowed, c.Request.Method+" is not supported here"))
+}
@@ -0,0 +1,37 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/farellandr/fullstack2024-test/logging"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRespondLeavesSharedErrorUnchanged(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	shared := Conflict("Already exists").With("hint", "retry later")
+
+	for _, id := range []string{"req-1", "req-2"} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		c.Writer.Header().Set(logging.RequestIDHeader, id)
+
+		Respond(c, shared)
+
+		var body map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("decode problem: %v", err)
+		}
+		if body["request_id"] != id || body["hint"] != "retry later" {
+			t.Errorf("response %s: request_id %v, hint %v", id, body["request_id"], body["hint"])
+		}
+	}
+
+	if _, ok := shared.Extensions["request_id"]; ok || len(shared.Extensions) != 1 {
+		t.Errorf("shared error extensions changed: %v", shared.Extensions)
+	}
+}
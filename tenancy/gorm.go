@@ -0,0 +1,103 @@
+package tenancy
+
+import (
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const tenantField = "TenantID"
+
+// Register installs the tenant callbacks on db. Queries, updates and deletes
+// of tenant-owned models are restricted to the context's tenant, creates are
+// stamped with it, and any of these fail with ErrMissingTenant when the
+// context carries no tenant and was not marked WithoutTenant. Raw SQL is not
+// rewritten and must filter on tenant_id itself.
+func Register(db *gorm.DB) error {
+	cb := db.Callback()
+
+	if err := cb.Create().Before("gorm:create").Register("tenancy:create", stampTenant); err != nil {
+		return err
+	}
+	if err := cb.Query().Before("gorm:query").Register("tenancy:query", scopeTenant); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("gorm:row").Register("tenancy:row", scopeTenant); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("tenancy:update", scopeTenant); err != nil {
+		return err
+	}
+	return cb.Delete().Before("gorm:delete").Register("tenancy:delete", scopeTenant)
+}
+
+func tenantSchemaField(db *gorm.DB) *schema.Field {
+	if db.Statement.Schema == nil {
+		return nil
+	}
+	return db.Statement.Schema.LookUpField(tenantField)
+}
+
+// statementTenant returns the tenant for a statement on a tenant-owned model,
+// or false when the statement is not to be scoped.
+func statementTenant(db *gorm.DB) (string, bool) {
+	ctx := db.Statement.Context
+	if bypassed(ctx) {
+		return "", false
+	}
+
+	tenant, ok := FromContext(ctx)
+	if !ok {
+		db.AddError(ErrMissingTenant)
+		return "", false
+	}
+
+	return tenant, true
+}
+
+func scopeTenant(db *gorm.DB) {
+	field := tenantSchemaField(db)
+	if field == nil || db.Error != nil {
+		return
+	}
+
+	tenant, ok := statementTenant(db)
+	if !ok {
+		return
+	}
+
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: tenant},
+	}})
+}
+
+func stampTenant(db *gorm.DB) {
+	field := tenantSchemaField(db)
+	if field == nil || db.Error != nil {
+		return
+	}
+
+	tenant, ok := statementTenant(db)
+	if !ok {
+		return
+	}
+
+	ctx := db.Statement.Context
+	rv := db.Statement.ReflectValue
+
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if err := field.Set(ctx, reflect.Indirect(rv.Index(i)), tenant); err != nil {
+				db.AddError(err)
+				return
+			}
+		}
+	case reflect.Struct:
+		if err := field.Set(ctx, rv, tenant); err != nil {
+			db.AddError(err)
+		}
+	}
+}
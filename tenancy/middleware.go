@@ -0,0 +1,54 @@
+package tenancy
+
+import (
+	"strings"
+
+	"github.com/farellandr/fullstack2024-test/config"
+	"github.com/farellandr/fullstack2024-test/problem"
+	"github.com/gin-gonic/gin"
+)
+
+// Middleware resolves the tenant of each request and stores it in the
+// request context. boundTenant returns the tenant the caller's credentials
+// are bound to, if any; such callers may not pick another tenant through the
+// header. Unbound callers are rejected unless crossTenant allows them to
+// choose one, with the header or else the configured default tenant.
+func Middleware(cfg config.TenancyConfig, boundTenant func(*gin.Context) string, crossTenant func(*gin.Context) bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requested := strings.ToLower(strings.TrimSpace(c.GetHeader(cfg.Header)))
+		tenant := boundTenant(c)
+
+		switch {
+		case tenant != "":
+			if requested != "" && requested != tenant {
+				problem.Respond(c, problem.Forbidden("Credentials are not valid for tenant "+requested))
+				return
+			}
+		case !crossTenant(c):
+			problem.Respond(c, problem.Forbidden("Credentials are not bound to a tenant"))
+			return
+		case requested != "":
+			tenant = requested
+		default:
+			tenant = cfg.Default
+		}
+
+		if tenant == "" {
+			problem.Respond(c, problem.BadRequest("Missing "+cfg.Header+" header"))
+			return
+		}
+		if !Valid(tenant) {
+			problem.Respond(c, problem.BadRequest("Invalid tenant ID"))
+			return
+		}
+
+		c.Request = c.Request.WithContext(WithTenant(c.Request.Context(), tenant))
+		c.Next()
+	}
+}
+
+// ID returns the tenant of the request, as resolved by Middleware.
+func ID(c *gin.Context) string {
+	tenant, _ := FromContext(c.Request.Context())
+	return tenant
+}
@@ -0,0 +1,58 @@
+// Package tenancy isolates the data of business units sharing this service.
+// The tenant of a request travels in its context.Context; gorm callbacks
+// registered by Register scope every query on a model with a TenantID field
+// to that tenant and stamp it on created rows.
+package tenancy
+
+import (
+	"context"
+	"errors"
+	"regexp"
+)
+
+type contextKey int
+
+const (
+	tenantKey contextKey = iota
+	bypassKey
+)
+
+var (
+	ErrMissingTenant = errors.New("tenancy: no tenant in context")
+	ErrInvalidTenant = errors.New("tenancy: invalid tenant ID")
+)
+
+var tenantPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// Valid reports whether id is a well-formed tenant ID.
+func Valid(id string) bool {
+	return tenantPattern.MatchString(id)
+}
+
+// WithTenant returns a context scoped to tenant id.
+func WithTenant(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantKey, id)
+}
+
+// FromContext returns the tenant a context is scoped to.
+func FromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	id, ok := ctx.Value(tenantKey).(string)
+	return id, ok && id != ""
+}
+
+// WithoutTenant returns a context whose queries deliberately span all
+// tenants, such as API key lookups made before the tenant is known.
+func WithoutTenant(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassKey, true)
+}
+
+func bypassed(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	b, _ := ctx.Value(bypassKey).(bool)
+	return b
+}
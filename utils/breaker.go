@@ -0,0 +1,202 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/farellandr/fullstack2024-test/metrics"
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrCircuitOpen is returned instead of running a Redis command while the
+// circuit breaker is open.
+var ErrCircuitOpen = errors.New("redis circuit breaker is open")
+
+// BreakerState is the state of a circuit breaker.
+type BreakerState int
+
+const (
+	// BreakerClosed lets every command through.
+	BreakerClosed BreakerState = iota
+	// BreakerHalfOpen lets a single probe through to test recovery.
+	BreakerHalfOpen
+	// BreakerOpen fails every command immediately.
+	BreakerOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerHalfOpen:
+		return "half-open"
+	case BreakerOpen:
+		return "open"
+	default:
+		return "closed"
+	}
+}
+
+// Breaker stops calling a dependency after Failures consecutive failures,
+// so that callers fall back at once instead of each waiting for a timeout.
+// After Cooldown it lets one call through as a probe: success closes the
+// breaker, failure opens it for another Cooldown.
+type Breaker struct {
+	Name     string
+	Failures int
+	Cooldown time.Duration
+
+	mu       sync.Mutex
+	state    BreakerState
+	failed   int
+	openedAt time.Time
+	probing  bool
+
+	// now is the clock; tests replace it.
+	now func() time.Time
+}
+
+func NewBreaker(name string, failures int, cooldown time.Duration) *Breaker {
+	b := &Breaker{Name: name, Failures: failures, Cooldown: cooldown, now: time.Now}
+	metrics.SetBreakerState(name, int(BreakerClosed))
+	return b
+}
+
+// State returns the current state, moving an open breaker whose cooldown
+// has passed to half-open.
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.cooledDown()
+	return b.state
+}
+
+// Allow reports whether a call may go ahead. Every allowed call must be
+// followed by Success, Failure or Release.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.cooledDown()
+	switch b.state {
+	case BreakerOpen:
+		return ErrCircuitOpen
+	case BreakerHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// Success records a call that reached the dependency.
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failed = 0
+	b.probing = false
+	b.setState(BreakerClosed)
+}
+
+// Failure records a call that failed to reach the dependency.
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failed++
+	if b.state == BreakerHalfOpen || b.failed >= b.Failures {
+		b.open()
+	}
+}
+
+// Release records a call that says nothing about the dependency, such as
+// one its caller gave up on.
+func (b *Breaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// Trip opens the breaker regardless of its state.
+func (b *Breaker) Trip() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.open()
+}
+
+func (b *Breaker) open() {
+	b.openedAt = b.now()
+	b.probing = false
+	b.setState(BreakerOpen)
+}
+
+func (b *Breaker) cooledDown() {
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.Cooldown {
+		b.setState(BreakerHalfOpen)
+	}
+}
+
+func (b *Breaker) setState(state BreakerState) {
+	if b.state == state {
+		return
+	}
+	b.state = state
+	metrics.SetBreakerState(b.Name, int(state))
+	metrics.BreakerTransition(b.Name, state.String())
+}
+
+// breakerHook runs every Redis command through a Breaker.
+type breakerHook struct {
+	breaker *Breaker
+}
+
+func (h breakerHook) BeforeProcess(ctx context.Context, _ redis.Cmder) (context.Context, error) {
+	return ctx, h.breaker.Allow()
+}
+
+func (h breakerHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	h.record(ctx, cmd.Err())
+	return nil
+}
+
+func (h breakerHook) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
+	return ctx, h.breaker.Allow()
+}
+
+func (h breakerHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if isOutage(cmd.Err()) {
+			err = cmd.Err()
+			break
+		}
+	}
+	h.record(ctx, err)
+	return nil
+}
+
+func (h breakerHook) record(ctx context.Context, err error) {
+	switch {
+	case errors.Is(err, ErrCircuitOpen):
+		// Rejected by this hook; nothing was sent.
+	case !isOutage(err):
+		h.breaker.Success()
+	case ctx.Err() != nil:
+		h.breaker.Release()
+	default:
+		h.breaker.Failure()
+	}
+}
+
+// isOutage tells errors reaching Redis apart from replies: a missing key or
+// an error returned by the server means Redis is up.
+func isOutage(err error) bool {
+	if err == nil || errors.Is(err, redis.Nil) {
+		return false
+	}
+	var reply redis.Error
+	return !errors.As(err, &reply)
+}
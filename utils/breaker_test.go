@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errUnreachable = errors.New("dial tcp: connection refused")
+
+// newTestBreaker returns a breaker opening after 3 failures for a minute,
+// and a function advancing its clock.
+func newTestBreaker(t *testing.T) (*Breaker, func(time.Duration)) {
+	t.Helper()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := NewBreaker(t.Name(), 3, time.Minute)
+	b.now = func() time.Time { return now }
+	return b, func(d time.Duration) { now = now.Add(d) }
+}
+
+// probe opens the breaker, waits out the cooldown and takes the probe.
+func probe(t *testing.T, b *Breaker, advance func(time.Duration)) {
+	t.Helper()
+
+	b.Trip()
+	advance(b.Cooldown)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("probe after cooldown: %v", err)
+	}
+}
+
+func TestBreakerOpensAfterThreshold(t *testing.T) {
+	b, advance := newTestBreaker(t)
+
+	for i := 1; i < b.Failures; i++ {
+		b.Failure()
+		if state := b.State(); state != BreakerClosed {
+			t.Fatalf("after %d failures: state %s, want closed", i, state)
+		}
+	}
+	b.Success()
+	for i := 1; i < b.Failures; i++ {
+		b.Failure()
+	}
+	if state := b.State(); state != BreakerClosed {
+		t.Fatalf("a success must reset the failure count: state %s", state)
+	}
+
+	b.Failure()
+	if state := b.State(); state != BreakerOpen {
+		t.Fatalf("after %d consecutive failures: state %s, want open", b.Failures, state)
+	}
+	if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("open breaker allowed a call: %v", err)
+	}
+
+	advance(b.Cooldown - time.Second)
+	if state := b.State(); state != BreakerOpen {
+		t.Errorf("before the cooldown: state %s, want open", state)
+	}
+	advance(time.Second)
+	if state := b.State(); state != BreakerHalfOpen {
+		t.Errorf("after the cooldown: state %s, want half-open", state)
+	}
+}
+
+func TestBreakerHalfOpenAllowsSingleProbe(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		result func(*Breaker)
+		want   BreakerState
+	}{
+		{"probe succeeds", (*Breaker).Success, BreakerClosed},
+		{"probe fails", (*Breaker).Failure, BreakerOpen},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b, advance := newTestBreaker(t)
+			probe(t, b, advance)
+
+			if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+				t.Fatalf("second call while probing: got %v, want ErrCircuitOpen", err)
+			}
+
+			tc.result(b)
+			if state := b.State(); state != tc.want {
+				t.Errorf("state %s, want %s", state, tc.want)
+			}
+			if tc.want == BreakerOpen {
+				advance(b.Cooldown)
+				if err := b.Allow(); err != nil {
+					t.Errorf("probe after another cooldown: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestBreakerHookIgnoresRejectedCalls(t *testing.T) {
+	b, _ := newTestBreaker(t)
+	hook := breakerHook{breaker: b}
+
+	for i := 1; i < b.Failures; i++ {
+		hook.record(context.Background(), errUnreachable)
+	}
+	for i := 0; i < 10; i++ {
+		hook.record(context.Background(), ErrCircuitOpen)
+	}
+	if state := b.State(); state != BreakerClosed {
+		t.Fatalf("ErrCircuitOpen counted as a failure: state %s", state)
+	}
+
+	hook.record(context.Background(), errUnreachable)
+	if state := b.State(); state != BreakerOpen {
+		t.Errorf("after %d outages: state %s, want open", b.Failures, state)
+	}
+}
+
+func TestBreakerHookReleasesCanceledProbe(t *testing.T) {
+	b, advance := newTestBreaker(t)
+	hook := breakerHook{breaker: b}
+	probe(t, b, advance)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	hook.record(ctx, context.Canceled)
+
+	if state := b.State(); state != BreakerHalfOpen {
+		t.Fatalf("canceled probe: state %s, want half-open", state)
+	}
+	if err := b.Allow(); err != nil {
+		t.Errorf("canceled probe was not released: %v", err)
+	}
+}
@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/nyaruka/phonenumbers"
+)
+
+var ErrInvalidPhoneNumber = errors.New("invalid phone number")
+
+// DefaultRegion is the ISO 3166-1 alpha-2 country assumed for phone numbers
+// and addresses that do not state one.
+func DefaultRegion() string {
+	return strings.ToUpper(getEnv("PHONE_DEFAULT_REGION", "ID"))
+}
+
+// IsSupportedRegion reports whether code is a country known to the numbering plan data.
+func IsSupportedRegion(code string) bool {
+	_, ok := phonenumbers.GetSupportedRegions()[code]
+	return ok
+}
+
+// NormalizePhoneNumber parses a phone number written in any common national or
+// international format and returns it in E.164 form. Numbers without a
+// country calling code are interpreted in region.
+func NormalizePhoneNumber(raw, region string) (string, error) {
+	number, err := phonenumbers.Parse(raw, region)
+	if err != nil {
+		return "", ErrInvalidPhoneNumber
+	}
+
+	if !phonenumbers.IsValidNumber(number) {
+		return "", ErrInvalidPhoneNumber
+	}
+
+	return phonenumbers.Format(number, phonenumbers.E164), nil
+}
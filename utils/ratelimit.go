@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// tokenBucketScript refills a bucket from the time elapsed since its last use
+// and takes one token if available. Redis' own clock is used so that every
+// replica sees the same time. Returns {allowed, tokens left * 1000}.
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local per_ms = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(bucket[1]) or capacity
+local ts = tonumber(bucket[2]) or now
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * per_ms)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tokens, 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(capacity / per_ms))
+return {allowed, math.floor(tokens * 1000)}
+`)
+
+// TakeToken takes one token from the bucket stored at key, which holds up to
+// capacity tokens and refills completely over window. It reports whether a
+// token was available and how many are left.
+func (r *RedisClient) TakeToken(ctx context.Context, key string, capacity int, window time.Duration) (bool, float64, error) {
+	perMs := float64(capacity) / float64(window.Milliseconds())
+
+	res, err := tokenBucketScript.Run(ctx, r.Client, []string{"ratelimit:" + key}, capacity, perMs).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+
+	return res[0] == 1, float64(res[1]) / 1000, nil
+}
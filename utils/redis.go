@@ -2,59 +2,71 @@ package utils
 
 import (
 	"context"
-	"encoding/json"
-	"log"
+	"fmt"
+	"log/slog"
 	"os"
 
+	"github.com/farellandr/fullstack2024-test/config"
+	"github.com/farellandr/fullstack2024-test/tracing"
 	"github.com/go-redis/redis/v8"
 )
 
 type RedisClient struct {
-	Client *redis.Client
-	Ctx    context.Context
+	Client  *redis.Client
+	Breaker *Breaker
 }
 
-func InitRedis() *RedisClient {
-	redisHost := getEnv("REDIS_HOST", "localhost")
-	redisPort := getEnv("REDIS_PORT", "6379")
-	redisAddr := redisHost + ":" + redisPort
-
+// InitRedis connects to Redis through a circuit breaker. If Redis does not
+// answer at startup the breaker starts open, so the first requests fall back
+// immediately rather than each waiting for a dial timeout.
+func InitRedis(cfg config.RedisConfig) *RedisClient {
 	client := redis.NewClient(&redis.Options{
-		Addr:     redisAddr,
-		Password: getEnv("REDIS_PASSWORD", ""),
-		DB:       0,
+		Addr:         cfg.Addr,
+		Password:     cfg.Password,
+		DB:           cfg.DB,
+		PoolSize:     cfg.PoolSize,
+		MinIdleConns: cfg.MinIdleConns,
+		DialTimeout:  cfg.DialTimeout,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		PoolTimeout:  cfg.PoolTimeout,
 	})
+	breaker := NewBreaker("redis", cfg.BreakerFailures, cfg.BreakerCooldown)
+	client.AddHook(tracing.RedisHook{})
+	client.AddHook(breakerHook{breaker: breaker})
 
-	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.DialTimeout+cfg.ReadTimeout)
+	defer cancel()
 
 	_, err := client.Ping(ctx).Result()
 	if err != nil {
-		log.Printf("Warning: Redis connection failed: %v", err)
+		breaker.Trip()
+		slog.Warn("Redis connection failed", "addr", cfg.Addr, "error", err, "retry_in", cfg.BreakerCooldown)
 	} else {
-		log.Println("Connected to Redis")
+		slog.Info("Connected to Redis", "addr", cfg.Addr)
 	}
 
-	return &RedisClient{
-		Client: client,
-		Ctx:    ctx,
-	}
+	return &RedisClient{Client: client, Breaker: breaker}
 }
 
-func (r *RedisClient) SetClientData(slug string, data interface{}) error {
-	jsonData, err := json.Marshal(data)
-	if err != nil {
-		return err
-	}
-
-	return r.Client.Set(r.Ctx, "client:"+slug, jsonData, 0).Err()
+// Ping checks that Redis answers. While the circuit breaker is open it fails
+// without trying; once the cooldown has passed it is the probe.
+func (r *RedisClient) Ping(ctx context.Context) error {
+	return r.Client.Ping(ctx).Err()
 }
 
-func (r *RedisClient) GetClientData(slug string) (string, error) {
-	return r.Client.Get(r.Ctx, "client:"+slug).Result()
+// CheckBreaker fails unless the circuit breaker is closed, for the health
+// report.
+func (r *RedisClient) CheckBreaker(context.Context) error {
+	if state := r.Breaker.State(); state != BreakerClosed {
+		return fmt.Errorf("circuit breaker is %s", state)
+	}
+	return nil
 }
 
-func (r *RedisClient) DeleteClientData(slug string) error {
-	return r.Client.Del(r.Ctx, "client:"+slug).Err()
+// Close releases the connection pool.
+func (r *RedisClient) Close() error {
+	return r.Client.Close()
 }
 
 func getEnv(key, fallback string) string {
@@ -2,10 +2,15 @@ package utils
 
 import (
 	"bytes"
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"mime/multipart"
 	"path/filepath"
+	"time"
+
+	"github.com/farellandr/fullstack2024-test/metrics"
+	"github.com/farellandr/fullstack2024-test/tracing"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
@@ -13,6 +18,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type S3Service struct {
@@ -33,7 +39,7 @@ func InitS3() *S3Service {
 	})
 
 	if err != nil {
-		log.Printf("Warning: Failed to create AWS session: %v", err)
+		slog.Warn("Failed to create AWS session", "error", err)
 		return nil
 	}
 
@@ -44,7 +50,24 @@ func InitS3() *S3Service {
 	}
 }
 
-func (s *S3Service) UploadFile(file *multipart.FileHeader) (string, error) {
+// HeadBucket checks that the bucket exists and the credentials can reach it.
+func (s *S3Service) HeadBucket(ctx context.Context) error {
+	ctx, span := tracing.StartClientSpan(ctx, "s3.HeadBucket", attribute.String("aws.s3.bucket", s.Bucket))
+
+	_, err := s.S3.HeadBucketWithContext(ctx, &s3.HeadBucketInput{Bucket: aws.String(s.Bucket)})
+	tracing.End(span, err)
+	return err
+}
+
+// UploadFile stores file under a random name in the tenant's folder of the
+// bucket and returns its public URL. The upload is abandoned when ctx ends.
+func (s *S3Service) UploadFile(ctx context.Context, tenant string, file *multipart.FileHeader) (url string, err error) {
+	ctx, span := tracing.StartClientSpan(ctx, "s3.PutObject",
+		attribute.String("aws.s3.bucket", s.Bucket),
+		attribute.Int64("aws.s3.size", file.Size),
+	)
+	defer func() { tracing.End(span, err) }()
+
 	openedFile, err := file.Open()
 	if err != nil {
 		return "", err
@@ -57,14 +80,17 @@ func (s *S3Service) UploadFile(file *multipart.FileHeader) (string, error) {
 	}
 
 	ext := filepath.Ext(file.Filename)
-	fileName := fmt.Sprintf("%s%s", uuid.New().String(), ext)
+	fileName := fmt.Sprintf("%s/%s%s", tenant, uuid.New().String(), ext)
+	span.SetAttributes(attribute.String("aws.s3.key", fileName))
 
-	_, err = s.Uploader.Upload(&s3manager.UploadInput{
+	start := time.Now()
+	_, err = s.Uploader.UploadWithContext(ctx, &s3manager.UploadInput{
 		Bucket:      aws.String(s.Bucket),
 		Key:         aws.String(fileName),
 		Body:        bytes.NewReader(buffer),
 		ContentType: aws.String(file.Header.Get("Content-Type")),
 	})
+	metrics.ObserveS3Upload(file.Size, time.Since(start), err)
 
 	if err != nil {
 		return "", err